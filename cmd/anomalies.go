@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/analysis"
+	"uber-extractor/internal/format"
+	"uber-extractor/internal/locations"
+)
+
+var anomalyThreshold float64
+
+var AnomaliesCmd = &cobra.Command{
+	Use:   "anomalies",
+	Short: "Flag trips with unusual fares or impossible data",
+	Long: `Flag completed trips whose fare per km or per minute is at least --threshold times above or
+below the median of trips between the same locations, in the same vehicle type and within the
+same 3-hour window of the day (with at least 3 such trips to compare). Also flag trips with
+impossible data: a negative duration, an end time before the begin time, or a fare for a trip
+with no distance.`,
+	Example: `  # Surge pricing and receipt errors over the last year
+  ue anomalies --last 365d
+
+  # Only fares at least 3x off the median, as CSV
+  ue anomalies --trips trips.json --threshold 3 -o csv`,
+	Args: cobra.NoArgs,
+	RunE: runAnomalies,
+}
+
+func init() {
+	addTripSourceFlags(AnomaliesCmd)
+	addClusterFlags(AnomaliesCmd)
+	addReportOutputFlag(AnomaliesCmd)
+	AnomaliesCmd.Flags().Float64Var(&anomalyThreshold, "threshold", analysis.DefaultAnomalyThreshold, "Flag fares this many times above or below the median")
+}
+
+func runAnomalies(cmd *cobra.Command, args []string) error {
+	outputFormat, err := parseReportOutput()
+	if err != nil {
+		return err
+	}
+
+	if anomalyThreshold <= 1 {
+		return fmt.Errorf("--threshold must be greater than 1")
+	}
+
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	tripList, err := loadLocatedTrips(context.Background(), registry)
+	if err != nil {
+		return err
+	}
+
+	anomalies := analysis.FindAnomalies(registry, tripList, time.Local, anomalyThreshold)
+
+	if outputFormat == reportTable && len(anomalies) == 0 {
+		fmt.Println("No anomalies found.")
+		return nil
+	}
+
+	var header []string
+	var rows [][]string
+	if outputFormat == reportCSV {
+		header = []string{"UUID", "BeginTime", "Kind", "Fare", "Currency", "VehicleType", "PickupLocationID", "DropoffLocationID", "Value", "Median", "Ratio", "Reason"}
+		for _, a := range anomalies {
+			rows = append(rows, []string{
+				a.Trip.UUID,
+				format.FormatTime(a.Trip.BeginTime),
+				string(a.Kind),
+				strconv.FormatFloat(a.Trip.Fare, 'f', 2, 64),
+				a.Trip.Currency,
+				a.Trip.VehicleType,
+				a.Trip.PickupLocationID,
+				a.Trip.DropoffLocationID,
+				strconv.FormatFloat(a.Value, 'f', 2, 64),
+				strconv.FormatFloat(a.Median, 'f', 2, 64),
+				strconv.FormatFloat(a.Ratio, 'f', 2, 64),
+				a.Reason,
+			})
+		}
+	} else {
+		header = []string{"DATE", "TRIP", "KIND", "FARE", "REASON"}
+		for _, a := range anomalies {
+			rows = append(rows, []string{
+				showTime(a.Trip.BeginTime.Local()),
+				truncate(a.Trip.UUID, 8),
+				string(a.Kind),
+				fmt.Sprintf("%.2f %s", a.Trip.Fare, a.Trip.Currency),
+				a.Reason,
+			})
+		}
+	}
+
+	return writeReport(os.Stdout, outputFormat, header, rows, anomalies)
+}
@@ -0,0 +1,297 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/auth"
+	"uber-extractor/internal/budget"
+	"uber-extractor/internal/format"
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/transform"
+	"uber-extractor/internal/trips"
+	"uber-extractor/internal/uberapi"
+)
+
+var (
+	budgetAmount    float64
+	budgetPeriod    string
+	budgetCurrency  string
+	budgetLocation  string
+	budgetVehicle   string
+	failOnProjected bool
+)
+
+var BudgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Compare spend with your budgets",
+	Long: `Report the spend on completed trips in the current week, month, quarter or year against each
+budget, with the spend projected to the end of the period from the run rate so far. Without
+--trips or a date range, trips since the start of the longest period are fetched. Exits with a
+non-zero status when a budget is exceeded (or, with --fail-on-projected, projected to be), so it
+can gate scripts.`,
+	Example: `  # Set a monthly budget and one for trips to or from the office
+  ue budget set transport --amount 800 --currency BRL
+  ue budget set office --amount 300 --currency BRL --location office
+
+  # How are we doing this month?
+  ue budget
+
+  # Fail a cron job when a budget is on track to be exceeded
+  ue budget --fail-on-projected || notify-send "Transport budget"`,
+	Args: cobra.NoArgs,
+	RunE: runBudget,
+}
+
+var BudgetSetCmd = &cobra.Command{
+	Use:   "set <name> --amount <amount> --currency <code>",
+	Short: "Add or replace a budget",
+	Long: `Add a budget, or replace the budget with the same name. Only completed trips paid in the
+budget's currency count towards it, optionally only those starting or ending at a location, or
+in a vehicle type. Fares in different currencies are never added up.`,
+	Example: `  # 200 per week on UberX trips
+  ue budget set uberx --amount 200 --currency BRL --period week --vehicle UberX`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBudgetSet,
+}
+
+var BudgetRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a budget",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBudgetRemove,
+}
+
+func init() {
+	addTripSourceFlags(BudgetCmd)
+	addClusterFlags(BudgetCmd)
+	addReportOutputFlag(BudgetCmd)
+	BudgetCmd.Flags().StringVar(&currency, "currency", format.DefaultCurrency, "Currency for fares without a currency symbol")
+	BudgetCmd.Flags().BoolVar(&failOnProjected, "fail-on-projected", false, "Also exit non-zero when the projected spend exceeds a budget")
+
+	BudgetSetCmd.Flags().Float64Var(&budgetAmount, "amount", 0, "Budget amount per period")
+	BudgetSetCmd.Flags().StringVar(&budgetPeriod, "period", string(budget.PeriodMonth), "Calendar period: week, month, quarter, year")
+	BudgetSetCmd.Flags().StringVar(&budgetCurrency, "currency", "", "Currency of the budget, e.g. BRL; only trips paid in it count")
+	BudgetSetCmd.Flags().StringVar(&budgetLocation, "location", "", "Only count trips starting or ending at this location")
+	BudgetSetCmd.Flags().StringVar(&budgetVehicle, "vehicle", "", "Only count trips in this vehicle type, e.g. UberX")
+	BudgetSetCmd.MarkFlagRequired("amount")
+	BudgetSetCmd.MarkFlagRequired("currency")
+
+	BudgetCmd.AddCommand(BudgetSetCmd)
+	BudgetCmd.AddCommand(BudgetRemoveCmd)
+}
+
+func runBudget(cmd *cobra.Command, args []string) error {
+	outputFormat, err := parseReportOutput()
+	if err != nil {
+		return err
+	}
+
+	config, err := budget.Load()
+	if err != nil {
+		return err
+	}
+
+	if len(config.Budgets) == 0 {
+		fmt.Println("No budgets set.")
+		fmt.Println("\nRun 'ue budget set <name> --amount <amount> --currency <code>' to add one.")
+		return nil
+	}
+
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	now := time.Now()
+	tripList, err := loadBudgetTrips(context.Background(), registry, config.Budgets, now)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := budget.Evaluate(config.Budgets, registry, tripList, strings.ToUpper(currency), now)
+	if err != nil {
+		return err
+	}
+
+	var header []string
+	var rows [][]string
+	if outputFormat == reportCSV {
+		header = []string{"Name", "Period", "Start", "End", "Currency", "LocationID", "VehicleType", "Amount", "Trips", "Spent", "Remaining", "Projected", "Status"}
+		for _, s := range statuses {
+			rows = append(rows, []string{
+				s.Budget.Name,
+				string(s.Budget.Period),
+				s.Start.Format("2006-01-02"),
+				s.End.Format("2006-01-02"),
+				s.Budget.Currency,
+				s.Budget.Location,
+				s.Budget.VehicleType,
+				strconv.FormatFloat(s.Budget.Amount, 'f', 2, 64),
+				strconv.Itoa(s.Trips),
+				strconv.FormatFloat(s.Spent, 'f', 2, 64),
+				strconv.FormatFloat(s.Remaining, 'f', 2, 64),
+				strconv.FormatFloat(s.Projected, 'f', 2, 64),
+				budgetState(s),
+			})
+		}
+	} else {
+		header = []string{"BUDGET", "PERIOD", "ONLY", "SPENT", "AMOUNT", "REMAINING", "PROJECTED", "STATUS"}
+		for _, s := range statuses {
+			amount := func(v float64) string {
+				return strings.TrimSpace(fmt.Sprintf("%.2f %s", v, s.Budget.Currency))
+			}
+			rows = append(rows, []string{
+				s.Budget.Name,
+				fmt.Sprintf("%s (%s)", s.Budget.Period, s.Start.Format("2006-01-02")),
+				budgetFilter(registry, s.Budget),
+				amount(s.Spent),
+				amount(s.Budget.Amount),
+				amount(s.Remaining),
+				amount(s.Projected),
+				budgetState(s),
+			})
+		}
+	}
+
+	if err := writeReport(os.Stdout, outputFormat, header, rows, statuses); err != nil {
+		return err
+	}
+
+	var over []string
+	for _, s := range statuses {
+		if s.Over || (failOnProjected && s.ProjectedOver()) {
+			over = append(over, s.Budget.Name)
+		}
+	}
+	if len(over) > 0 {
+		// The report already explains the failure.
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		return fmt.Errorf("%w: %s", budget.ErrOverBudget, strings.Join(over, ", "))
+	}
+	return nil
+}
+
+// loadBudgetTrips loads trips from the trip source flags, or fetches them
+// from the start of the longest current budget period until now.
+func loadBudgetTrips(ctx context.Context, registry *locations.Registry, budgets []budget.Budget, now time.Time) ([]trips.Trip, error) {
+	if hasTripSource() {
+		return loadLocatedTrips(ctx, registry)
+	}
+
+	clusterConfig, err := parseClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	start := now
+	for _, b := range budgets {
+		if s, _ := b.Period.Range(now); s.Before(start) {
+			start = s
+		}
+	}
+
+	creds, err := auth.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	tripList, err := fetchTrips(ctx, uberapi.NewClient(creds.Cookie), start, now)
+	if err != nil {
+		return nil, err
+	}
+
+	transform.LocateTrips(tripList, locations.NewProcessorWithConfig(registry, clusterConfig))
+	return tripList, nil
+}
+
+func budgetFilter(registry *locations.Registry, b budget.Budget) string {
+	var parts []string
+	if b.Location != "" {
+		parts = append(parts, truncate(locationName(registry, b.Location), 30))
+	}
+	if b.VehicleType != "" {
+		parts = append(parts, b.VehicleType)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func budgetState(s budget.Status) string {
+	switch {
+	case s.Over:
+		return "over"
+	case s.ProjectedOver():
+		return "projected over"
+	default:
+		return "ok"
+	}
+}
+
+func runBudgetSet(cmd *cobra.Command, args []string) error {
+	period, err := budget.ParsePeriod(budgetPeriod)
+	if err != nil {
+		return err
+	}
+
+	b := budget.Budget{
+		Name:        args[0],
+		Period:      period,
+		Amount:      budgetAmount,
+		Currency:    strings.ToUpper(strings.TrimSpace(budgetCurrency)),
+		VehicleType: strings.TrimSpace(budgetVehicle),
+	}
+
+	if budgetLocation != "" {
+		registry, err := locations.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load locations: %w", err)
+		}
+		loc, err := registry.Find(budgetLocation)
+		if err != nil {
+			return err
+		}
+		b.Location = loc.ID
+	}
+
+	config, err := budget.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := config.Set(b); err != nil {
+		return err
+	}
+
+	if err := budget.Save(config); err != nil {
+		return err
+	}
+
+	fmt.Printf("Set budget %s: %.2f %s per %s\n", b.Name, b.Amount, b.Currency, b.Period)
+	return nil
+}
+
+func runBudgetRemove(cmd *cobra.Command, args []string) error {
+	config, err := budget.Load()
+	if err != nil {
+		return err
+	}
+
+	removed, err := config.Remove(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := budget.Save(config); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed budget %s\n", removed.Name)
+	return nil
+}
@@ -12,7 +12,7 @@ var RootCmd = &cobra.Command{
 	Short: "CLI tool for extracting and analyzing Uber trip data",
 	Long:  `A CLI tool for extracting, analyzing, and exporting Uber trip data from Uber's GraphQL API.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 			Level: slog.LevelInfo,
 		})
 		slog.SetDefault(slog.New(handler))
@@ -28,6 +28,11 @@ func init() {
 	RootCmd.AddCommand(StatusCmd)
 	RootCmd.AddCommand(TripsCmd)
 	RootCmd.AddCommand(LocationsCmd)
+	RootCmd.AddCommand(ODMatrixCmd)
+	RootCmd.AddCommand(CommutesCmd)
+	RootCmd.AddCommand(AnomaliesCmd)
+	RootCmd.AddCommand(BudgetCmd)
+	RootCmd.AddCommand(HeatmapCmd)
 }
 
 func Execute() error {
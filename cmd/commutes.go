@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/analysis"
+	"uber-extractor/internal/locations"
+)
+
+var commuteMinTrips int
+
+var CommutesCmd = &cobra.Command{
+	Use:   "commutes",
+	Short: "Detect recurring commutes in your trip history",
+	Long: `Find trips that recur between the same two locations at a similar local time of day, in at
+least two different weeks, separately for weekdays and weekends. For each commute show the days
+it happens on, the typical departure time, how often it is taken and what it costs per month.
+Of two commutes in opposite directions, the earlier one is the commute and the later one the
+return commute. 'ue trips' tags every completed trip with its commute type.`,
+	Example: `  # Commutes over the last 6 months
+  ue commutes --last 180d
+
+  # Only commutes taken at least 8 times, as JSON
+  ue commutes --trips trips.json --min-trips 8 -o json`,
+	Args: cobra.NoArgs,
+	RunE: runCommutes,
+}
+
+func init() {
+	addTripSourceFlags(CommutesCmd)
+	addClusterFlags(CommutesCmd)
+	addReportOutputFlag(CommutesCmd)
+	CommutesCmd.Flags().IntVar(&commuteMinTrips, "min-trips", analysis.DefaultCommuteMinTrips, "Minimum number of trips for a commute")
+}
+
+func runCommutes(cmd *cobra.Command, args []string) error {
+	outputFormat, err := parseReportOutput()
+	if err != nil {
+		return err
+	}
+
+	if commuteMinTrips < 2 {
+		return fmt.Errorf("--min-trips must be at least 2")
+	}
+
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	tripList, err := loadLocatedTrips(context.Background(), registry)
+	if err != nil {
+		return err
+	}
+
+	commutes := analysis.DetectCommutes(registry, tripList, time.Local, commuteMinTrips)
+
+	if outputFormat == reportTable && len(commutes) == 0 {
+		fmt.Println("No commutes found.")
+		return nil
+	}
+
+	var header []string
+	var rows [][]string
+	if outputFormat == reportCSV {
+		header = []string{"Kind", "PickupLocationID", "PickupLabel", "DropoffLocationID", "DropoffLabel", "Weekdays", "Departure", "Trips", "TripsPerWeek", "Currency", "TotalFare", "MonthlyCost"}
+		for _, c := range commutes {
+			rows = append(rows, []string{
+				string(c.Kind),
+				c.PickupID,
+				c.PickupLabel,
+				c.DropoffID,
+				c.DropoffLabel,
+				strings.Join(c.Weekdays, " "),
+				c.Departure,
+				strconv.Itoa(c.Trips),
+				strconv.FormatFloat(c.PerWeek, 'f', 1, 64),
+				c.Currency,
+				strconv.FormatFloat(c.TotalFare, 'f', 2, 64),
+				strconv.FormatFloat(c.MonthlyCost, 'f', 2, 64),
+			})
+		}
+	} else {
+		header = []string{"KIND", "FROM", "TO", "DAYS", "DEPARTURE", "TRIPS", "PER WEEK", "MONTHLY COST"}
+		for _, c := range commutes {
+			rows = append(rows, []string{
+				string(c.Kind),
+				truncate(locationName(registry, c.PickupID), 30),
+				truncate(locationName(registry, c.DropoffID), 30),
+				strings.Join(c.Weekdays, " "),
+				c.Departure,
+				strconv.Itoa(c.Trips),
+				fmt.Sprintf("%.1f", c.PerWeek),
+				fmt.Sprintf("%.2f %s", c.MonthlyCost, c.Currency),
+			})
+		}
+	}
+
+	return writeReport(os.Stdout, outputFormat, header, rows, commutes)
+}
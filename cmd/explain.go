@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/locations"
+)
+
+var (
+	explainAddress string
+	explainLat     float64
+	explainLon     float64
+)
+
+var LocationsExplainCmd = &cobra.Command{
+	Use:   "explain <location> [location]",
+	Short: "Explain why an address was or wasn't merged into a location",
+	Long: `Compare an address and position against a saved location using the same rules as clustering:
+exact normalized address, distance within the location's radius, and fuzzy address similarity
+weighted by distance. With two locations, the first one's canonical address and coordinates are
+compared against the second.`,
+	Example: `  # Why weren't these two locations merged?
+  ue locations explain loc-3 loc-7
+
+  # Would a pickup here be merged into the office?
+  ue locations explain office --address "1725 Slough Ave" --lat 41.4092 --lon -75.6621`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runLocationsExplain,
+}
+
+func init() {
+	addClusterFlags(LocationsExplainCmd)
+	LocationsExplainCmd.Flags().StringVar(&explainAddress, "address", "", "Address to compare against the location")
+	LocationsExplainCmd.Flags().Float64Var(&explainLat, "lat", 0, "Latitude of the address")
+	LocationsExplainCmd.Flags().Float64Var(&explainLon, "lon", 0, "Longitude of the address")
+
+	LocationsCmd.AddCommand(LocationsExplainCmd)
+}
+
+func runLocationsExplain(cmd *cobra.Command, args []string) error {
+	clusterConfig, err := parseClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	target, err := registry.Find(args[len(args)-1])
+	if err != nil {
+		return err
+	}
+
+	address, lat, lon := explainAddress, explainLat, explainLon
+	if len(args) == 2 {
+		source, err := registry.Find(args[0])
+		if err != nil {
+			return err
+		}
+		address, lat, lon = source.CanonicalAddress, source.AvgLat, source.AvgLon
+	} else if address == "" || !cmd.Flags().Changed("lat") || !cmd.Flags().Changed("lon") {
+		return fmt.Errorf("--address, --lat and --lon are required when comparing against a single location")
+	}
+
+	lp := locations.NewProcessorWithConfig(registry, clusterConfig)
+	e := lp.Explain(address, lat, lon, *target)
+
+	result := "not merged"
+	if e.Match {
+		result = "merged"
+	}
+
+	fmt.Printf("Address:      %s\n", e.Address)
+	fmt.Printf("Compared to:  %s (%s)\n", e.LocationAddress, target.Name())
+	fmt.Printf("Distance:     %.0f m (radius %.0f m)\n", e.Distance, e.Radius)
+	if e.NumbersConflict {
+		fmt.Printf("Similarity:   0.00 (house numbers differ)\n")
+	} else {
+		fmt.Printf("Similarity:   %.2f (common: %s)\n", e.Similarity, strings.Join(e.CommonTokens, " "))
+	}
+	fmt.Printf("Confidence:   %.2f (minimum %.2f)\n", e.Confidence, e.MinConfidence)
+	fmt.Printf("Result:       %s, %s\n", result, e.Reason)
+	return nil
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/analysis"
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+const (
+	heatmapTrips = "trips"
+	heatmapSpend = "spend"
+)
+
+// heatmapShades go from an empty cell to the busiest one.
+var heatmapShades = []string{"  ", "░░", "▒▒", "▓▓", "██"}
+
+var (
+	heatmapValue    string
+	heatmapLocation string
+	heatmapVehicle  string
+	heatmapCurrency string
+	heatmapTimezone string
+)
+
+var HeatmapCmd = &cobra.Command{
+	Use:   "heatmap",
+	Short: "Show when you ride most by weekday and hour",
+	Long: `Render a 7x24 grid of completed trips by weekday and hour of their begin time in your time
+zone, shaded from quiet to busiest. With --value spend the grid shows the fares paid instead of
+the number of trips. Limit the trips to those starting or ending at a location or in a vehicle
+type, and print CSV or JSON with -o.`,
+	Example: `  # When do we ride most?
+  ue heatmap --last 365d
+
+  # When are rides to or from the office most expensive?
+  ue heatmap --trips trips.json --value spend --location office
+
+  # UberX trips in another time zone, as CSV
+  ue heatmap --trips trips.json --vehicle UberX --timezone America/Sao_Paulo -o csv`,
+	Args: cobra.NoArgs,
+	RunE: runHeatmap,
+}
+
+func init() {
+	addTripSourceFlags(HeatmapCmd)
+	addClusterFlags(HeatmapCmd)
+	addReportOutputFlag(HeatmapCmd)
+	HeatmapCmd.Flags().StringVar(&heatmapValue, "value", heatmapTrips, "Value per cell: trips, spend")
+	HeatmapCmd.Flags().StringVar(&heatmapLocation, "location", "", "Only trips starting or ending at this location")
+	HeatmapCmd.Flags().StringVar(&heatmapVehicle, "vehicle", "", "Only trips in this vehicle type, e.g. UberX")
+	HeatmapCmd.Flags().StringVar(&heatmapCurrency, "currency", "", "Only trips paid in this currency")
+	HeatmapCmd.Flags().StringVar(&heatmapTimezone, "timezone", "", "IANA time zone, e.g. America/Sao_Paulo (default: local time zone)")
+}
+
+type heatmapReport struct {
+	Value    string         `json:"value"`
+	Currency string         `json:"currency,omitempty"`
+	Timezone string         `json:"timezone"`
+	Weekdays []string       `json:"weekdays"`
+	Cells    [7][24]float64 `json:"cells"`
+	Max      float64        `json:"max"`
+	Total    float64        `json:"total"`
+}
+
+func runHeatmap(cmd *cobra.Command, args []string) error {
+	outputFormat, err := parseReportOutput()
+	if err != nil {
+		return err
+	}
+
+	value := strings.ToLower(heatmapValue)
+	if value != heatmapTrips && value != heatmapSpend {
+		return fmt.Errorf("invalid --value %q: expected trips or spend", heatmapValue)
+	}
+
+	tz := time.Local
+	if heatmapTimezone != "" {
+		tz, err = time.LoadLocation(heatmapTimezone)
+		if err != nil {
+			return fmt.Errorf("invalid time zone %q: %w", heatmapTimezone, err)
+		}
+	}
+
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	var locationID string
+	if heatmapLocation != "" {
+		loc, err := registry.Find(heatmapLocation)
+		if err != nil {
+			return err
+		}
+		locationID = loc.ID
+	}
+
+	tripList, err := loadLocatedTrips(context.Background(), registry)
+	if err != nil {
+		return err
+	}
+
+	if locationID != "" {
+		tripList = analysis.TripsAt(registry, locationID, tripList)
+	}
+	tripList = filterHeatmapTrips(tripList)
+
+	currency := strings.ToUpper(heatmapCurrency)
+	if value == heatmapSpend && currency == "" {
+		currencies := make(map[string]bool)
+		for _, trip := range tripList {
+			if trip.Status == trips.StatusCompleted {
+				currencies[trip.Currency] = true
+				currency = trip.Currency
+			}
+		}
+		if len(currencies) > 1 {
+			return fmt.Errorf("trips were paid in %d currencies; choose one with --currency", len(currencies))
+		}
+	}
+
+	h := analysis.BuildHeatmap(tripList, tz, value == heatmapSpend)
+
+	report := heatmapReport{Value: value, Timezone: tz.String(), Cells: h.Cells, Max: h.Max, Total: h.Total}
+	if value == heatmapSpend {
+		report.Currency = currency
+	}
+	for i := range h.Cells {
+		report.Weekdays = append(report.Weekdays, h.Weekday(i).String()[:3])
+	}
+
+	if outputFormat == reportTable {
+		printHeatmap(report)
+		return nil
+	}
+
+	header := []string{"Weekday"}
+	for hour := range 24 {
+		header = append(header, strconv.Itoa(hour))
+	}
+	var rows [][]string
+	for i, row := range h.Cells {
+		record := []string{report.Weekdays[i]}
+		for _, v := range row {
+			record = append(record, heatmapNumber(v, value))
+		}
+		rows = append(rows, record)
+	}
+
+	return writeReport(os.Stdout, outputFormat, header, rows, report)
+}
+
+func filterHeatmapTrips(tripList []trips.Trip) []trips.Trip {
+	var result []trips.Trip
+	for _, trip := range tripList {
+		if heatmapVehicle != "" && !strings.EqualFold(trip.VehicleType, heatmapVehicle) {
+			continue
+		}
+		if heatmapCurrency != "" && !strings.EqualFold(trip.Currency, heatmapCurrency) {
+			continue
+		}
+		result = append(result, trip)
+	}
+	return result
+}
+
+func heatmapNumber(v float64, value string) string {
+	if value == heatmapSpend {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+func printHeatmap(report heatmapReport) {
+	title := "Trips"
+	if report.Value == heatmapSpend {
+		title = strings.TrimSpace("Spend " + report.Currency)
+	}
+	fmt.Printf("%s by weekday and hour (%s)\n\n", title, report.Timezone)
+
+	if report.Total == 0 {
+		fmt.Println("No completed trips.")
+		return
+	}
+
+	fmt.Print("     ")
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Printf("%-6d", hour)
+	}
+	fmt.Println(" TOTAL")
+
+	for i, row := range report.Cells {
+		total := 0.0
+		var b strings.Builder
+		for _, v := range row {
+			b.WriteString(heatmapShade(v, report.Max))
+			total += v
+		}
+		fmt.Printf("%-5s%s %s\n", report.Weekdays[i], b.String(), heatmapNumber(total, report.Value))
+	}
+
+	fmt.Printf("\n%s up to 25%%  %s up to 50%%  %s up to 75%%  %s up to %s (busiest hour)\n",
+		heatmapShades[1], heatmapShades[2], heatmapShades[3], heatmapShades[4], heatmapNumber(report.Max, report.Value))
+}
+
+func heatmapShade(v, maxValue float64) string {
+	if v <= 0 || maxValue <= 0 {
+		return heatmapShades[0]
+	}
+	level := int(math.Ceil(v / maxValue * float64(len(heatmapShades)-1)))
+	return heatmapShades[min(level, len(heatmapShades)-1)]
+}
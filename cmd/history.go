@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/locations"
+)
+
+var historyLimit int
+
+var LocationsHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List recent changes to the location registry",
+	Long: `List the snapshots taken each time the location registry was saved, newest first, with the
+command that changed it and a summary of the change. The last 10 snapshots are kept.`,
+	Example: `  # What changed the registry recently?
+  ue locations history
+
+  # Undo the last change
+  ue locations restore 12`,
+	Args: cobra.NoArgs,
+	RunE: runLocationsHistory,
+}
+
+var LocationsRestoreCmd = &cobra.Command{
+	Use:   "restore <snapshot>",
+	Short: "Roll the location registry back to a snapshot",
+	Long: `Restore the location registry to how it was before the change recorded by a snapshot,
+undoing that change and every later one. The restore is recorded as a new snapshot, so it can
+be undone as well.`,
+	Example: `  # Undo a bad merge listed as snapshot 12
+  ue locations restore 12`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLocationsRestore,
+}
+
+func init() {
+	LocationsHistoryCmd.Flags().IntVar(&historyLimit, "limit", 0, "Maximum number of snapshots to show")
+
+	LocationsCmd.AddCommand(LocationsHistoryCmd)
+	LocationsCmd.AddCommand(LocationsRestoreCmd)
+}
+
+func runLocationsHistory(cmd *cobra.Command, args []string) error {
+	snapshots, err := locations.History()
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots yet.")
+		return nil
+	}
+
+	if historyLimit > 0 && len(snapshots) > historyLimit {
+		snapshots = snapshots[:historyLimit]
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SNAPSHOT\tTIME\tUSER\tCOMMAND\tCHANGES")
+	for _, s := range snapshots {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n",
+			s.ID,
+			s.Time.Local().Format("2006-01-02 15:04:05"),
+			s.User,
+			truncate(s.Command, 40),
+			s.Changes,
+		)
+	}
+	return tw.Flush()
+}
+
+func runLocationsRestore(cmd *cobra.Command, args []string) error {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid snapshot %q: expected a number from 'ue locations history'", args[0])
+	}
+
+	snapshot, err := locations.Restore(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored the registry to before snapshot %d (%s, %s)\n", snapshot.ID, snapshot.Command, snapshot.Time.Local().Format("2006-01-02 15:04:05"))
+	return nil
+}
@@ -3,11 +3,20 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+var (
+	clearLabel   bool
+	tripsFile    string
+	splitVariant string
 )
 
 var LocationsCmd = &cobra.Command{
@@ -17,11 +26,99 @@ var LocationsCmd = &cobra.Command{
 	Example: `  # List all saved locations
   ue locations
 
-  # Show details about location clustering
-  ue locations`,
+  # Label a location and use the label instead of its ID
+  ue locations label loc-3 home
+  ue locations rename home apartment
+  ue locations delete apartment
+
+  # Merge two locations and split an address variant back out
+  ue locations merge loc-3 loc-7
+  ue locations split loc-5 --variant "1725 slough avenue - loading dock" --trips trips.json`,
 	RunE: runLocations,
 }
 
+var LocationsLabelCmd = &cobra.Command{
+	Use:   "label <location> [label]",
+	Short: "Set or clear the label of a location",
+	Long:  `Assign a label such as "home", "office" or "airport" to a location. Labels are shown in exports and can be used anywhere a location ID is accepted.`,
+	Example: `  # Label a location
+  ue locations label loc-3 home
+
+  # Clear a label
+  ue locations label home --clear`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runLocationsLabel,
+}
+
+var LocationsRenameCmd = &cobra.Command{
+	Use:   "rename <location> <new-label>",
+	Short: "Rename the label of a location",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runLocationsRename,
+}
+
+var LocationsDeleteCmd = &cobra.Command{
+	Use:   "delete <location>",
+	Short: "Delete a location from the registry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runLocationsDelete,
+}
+
+var LocationsMergeCmd = &cobra.Command{
+	Use:   "merge <target> <source>...",
+	Short: "Merge locations into a single location",
+	Long: `Merge one or more source locations into the target location, combining visit counts,
+visit-weighted coordinates, address variants and first/last seen times. Merged IDs keep
+resolving to the target location.`,
+	Example: `  # Merge loc-7 into loc-3
+  ue locations merge loc-3 loc-7
+
+  # Also re-point trips in a JSON export
+  ue locations merge office loc-7 loc-9 --trips trips.json`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runLocationsMerge,
+}
+
+var LocationsSplitCmd = &cobra.Command{
+	Use:   "split <location> --variant <address>",
+	Short: "Split an address variant into its own location",
+	Long: `Carve an address variant out of a location into a new location. The JSON trips export
+given with --trips is required: the new location's coordinates, visit count and first/last seen
+times are computed from the trips to the variant, and those trips are re-pointed to it. Addresses
+are compared after normalizing them for --address-locale.`,
+	Example: `  # Split an entrance into its own location
+  ue locations split loc-5 --variant "1725 slough avenue - loading dock" --trips trips.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLocationsSplit,
+}
+
+var LocationsRadiusCmd = &cobra.Command{
+	Use:   "radius <location> <meters>",
+	Short: "Set the clustering radius of a location",
+	Long:  `Set a custom clustering radius for a location, e.g. for airports or malls whose entrances are far apart. A radius of 0 restores the default threshold.`,
+	Example: `  # Treat everything within 300 m of the airport as the same location
+  ue locations radius airport 300`,
+	Args: cobra.ExactArgs(2),
+	RunE: runLocationsRadius,
+}
+
+func init() {
+	LocationsLabelCmd.Flags().BoolVar(&clearLabel, "clear", false, "Remove the label from the location")
+	LocationsMergeCmd.Flags().StringVar(&tripsFile, "trips", "", "JSON trips export whose location IDs should be updated")
+	LocationsSplitCmd.Flags().StringVar(&tripsFile, "trips", "", "JSON trips export whose location IDs should be updated")
+	LocationsSplitCmd.Flags().StringVar(&splitVariant, "variant", "", "Address variant to split into a new location")
+	LocationsSplitCmd.Flags().StringVar(&addressLocale, "address-locale", locations.LocaleAuto, "Locale for address normalization: auto, "+strings.Join(locations.NormalizerLocales(), ", "))
+	LocationsSplitCmd.MarkFlagRequired("variant")
+	LocationsSplitCmd.MarkFlagRequired("trips")
+
+	LocationsCmd.AddCommand(LocationsLabelCmd)
+	LocationsCmd.AddCommand(LocationsRenameCmd)
+	LocationsCmd.AddCommand(LocationsDeleteCmd)
+	LocationsCmd.AddCommand(LocationsMergeCmd)
+	LocationsCmd.AddCommand(LocationsSplitCmd)
+	LocationsCmd.AddCommand(LocationsRadiusCmd)
+}
+
 func runLocations(cmd *cobra.Command, args []string) error {
 	registry, err := locations.Load()
 	if err != nil {
@@ -37,14 +134,15 @@ func runLocations(cmd *cobra.Command, args []string) error {
 	tw := tabwriter.NewWriter(os.Stdout, 10, 8, 3, ' ', 0)
 	defer tw.Flush()
 
-	fmt.Fprintln(tw, "ID\t\tVISITS\tCOORDINATES\t\tADDRESS")
+	fmt.Fprintln(tw, "ID\tLABEL\tVISITS\tCOORDINATES\t\tADDRESS")
 	for _, loc := range registry.Locations {
 		coords := fmt.Sprintf("%.6f, %.6f", loc.AvgLat, loc.AvgLon)
-		fmt.Fprintf(tw, "%s\t\t%d\t%s\t\t%s\n",
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t\t%s\n",
 			loc.ID,
+			loc.Label,
 			loc.VisitCount,
 			coords,
-			truncate(loc.CanonicalAddress, 40),
+			truncate(loc.DisplayAddress(), 40),
 		)
 	}
 
@@ -52,3 +150,220 @@ func runLocations(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runLocationsLabel(cmd *cobra.Command, args []string) error {
+	label := ""
+	switch {
+	case clearLabel && len(args) == 2:
+		return fmt.Errorf("cannot use --clear with a label")
+	case !clearLabel && len(args) == 1:
+		return fmt.Errorf("missing label (use --clear to remove it)")
+	case len(args) == 2:
+		label = args[1]
+	}
+
+	return updateRegistry(func(registry *locations.Registry) error {
+		loc, err := registry.Find(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := registry.SetLabel(loc.ID, label); err != nil {
+			return err
+		}
+
+		if label == "" {
+			fmt.Printf("Cleared label of %s\n", loc.ID)
+		} else {
+			fmt.Printf("Labeled %s as %q\n", loc.ID, loc.Label)
+		}
+		return nil
+	})
+}
+
+func runLocationsRename(cmd *cobra.Command, args []string) error {
+	return updateRegistry(func(registry *locations.Registry) error {
+		loc, err := registry.Find(args[0])
+		if err != nil {
+			return err
+		}
+
+		if loc.Label == "" {
+			return fmt.Errorf("%s has no label; use 'ue locations label' to assign one", loc.ID)
+		}
+
+		oldLabel := loc.Label
+		if err := registry.SetLabel(loc.ID, args[1]); err != nil {
+			return err
+		}
+
+		fmt.Printf("Renamed %q to %q (%s)\n", oldLabel, loc.Label, loc.ID)
+		return nil
+	})
+}
+
+func runLocationsDelete(cmd *cobra.Command, args []string) error {
+	return updateRegistry(func(registry *locations.Registry) error {
+		deleted, err := registry.Delete(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Deleted %s (%s)\n", deleted.Name(), truncate(deleted.DisplayAddress(), 40))
+		return nil
+	})
+}
+
+func runLocationsMerge(cmd *cobra.Command, args []string) error {
+	tripList, err := loadTripsFile(tripsFile)
+	if err != nil {
+		return err
+	}
+
+	err = updateRegistry(func(registry *locations.Registry) error {
+		target, err := registry.Find(args[0])
+		if err != nil {
+			return err
+		}
+		targetID := target.ID
+
+		for _, ref := range args[1:] {
+			source, err := registry.Find(ref)
+			if err != nil {
+				return err
+			}
+			sourceID := source.ID
+
+			merged, err := registry.Merge(targetID, sourceID)
+			if err != nil {
+				return err
+			}
+
+			repointed := repointTrips(tripList, registry, func(id, _ string) bool { return id == sourceID }, targetID)
+			fmt.Printf("Merged %s into %s (%d visits, %d trips re-pointed)\n", sourceID, merged.Name(), merged.VisitCount, repointed)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return saveTripsFile(tripsFile, tripList)
+}
+
+func runLocationsSplit(cmd *cobra.Command, args []string) error {
+	if err := locations.LoadAbbreviations(); err != nil {
+		return err
+	}
+	normalizer, err := locations.GetNormalizer(addressLocale)
+	if err != nil {
+		return err
+	}
+
+	tripList, err := loadTripsFile(tripsFile)
+	if err != nil {
+		return err
+	}
+
+	variant := normalizer.Normalize(splitVariant)
+
+	err = updateRegistry(func(registry *locations.Registry) error {
+		loc, err := registry.Find(args[0])
+		if err != nil {
+			return err
+		}
+		origID := loc.ID
+
+		matches := func(id, address string) bool {
+			return id == origID && normalizer.Normalize(address) == variant
+		}
+
+		var visits []locations.Visit
+		for _, trip := range tripList {
+			if matches(trip.PickupLocationID, trip.PickupAddress) {
+				visits = append(visits, locations.Visit{TripUUID: trip.UUID, Role: locations.VisitPickup, Time: trip.BeginTime, Lat: trip.PickupLat, Lon: trip.PickupLon})
+			}
+			if matches(trip.DropoffLocationID, trip.DropoffAddress) {
+				visits = append(visits, locations.Visit{TripUUID: trip.UUID, Role: locations.VisitDropoff, Time: trip.EndTime, Lat: trip.DropoffLat, Lon: trip.DropoffLon})
+			}
+		}
+
+		newLoc, err := registry.Split(origID, variant, normalizer, visits)
+		if err != nil {
+			return err
+		}
+		newID := newLoc.ID
+
+		repointed := repointTrips(tripList, registry, matches, newID)
+		fmt.Printf("Split %q from %s into %s (%d trips re-pointed)\n", variant, origID, newID, repointed)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return saveTripsFile(tripsFile, tripList)
+}
+
+func runLocationsRadius(cmd *cobra.Command, args []string) error {
+	meters, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid radius: %w", err)
+	}
+
+	return updateRegistry(func(registry *locations.Registry) error {
+		if err := registry.SetRadius(args[0], meters); err != nil {
+			return err
+		}
+
+		loc, _ := registry.Find(args[0])
+		if meters == 0 {
+			fmt.Printf("Reset radius of %s to the default threshold\n", loc.Name())
+		} else {
+			fmt.Printf("Set radius of %s to %.0f m\n", loc.Name(), meters)
+		}
+		return nil
+	})
+}
+
+func repointTrips(tripList []trips.Trip, registry *locations.Registry, match func(id, address string) bool, newID string) int {
+	label := registry.Label(newID)
+
+	count := 0
+	for i := range tripList {
+		trip := &tripList[i]
+		changed := false
+		if match(trip.PickupLocationID, trip.PickupAddress) {
+			trip.PickupLocationID = newID
+			trip.PickupLabel = label
+			changed = true
+		}
+		if match(trip.DropoffLocationID, trip.DropoffAddress) {
+			trip.DropoffLocationID = newID
+			trip.DropoffLabel = label
+			changed = true
+		}
+		if changed {
+			count++
+		}
+	}
+	return count
+}
+
+func loadTripsFile(path string) ([]trips.Trip, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return trips.LoadFile(path)
+}
+
+func saveTripsFile(path string, tripList []trips.Trip) error {
+	if path == "" {
+		return nil
+	}
+	return trips.SaveFile(path, tripList)
+}
+
+func updateRegistry(update func(registry *locations.Registry) error) error {
+	return locations.Update(update)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/locations"
+)
+
+var (
+	nearLat    float64
+	nearLon    float64
+	nearRadius float64
+	nearLimit  int
+)
+
+var LocationsNearCmd = &cobra.Command{
+	Use:   "near [location | lat,lon]",
+	Short: "Find saved locations near a point",
+	Long: `List saved locations within --radius meters of a point, nearest first. The point is given
+with --lat/--lon, as "lat,lon", or as a saved location. Without --radius only the nearest
+location is shown.`,
+	Example: `  # Which saved location is closest to this coordinate?
+  ue locations near --lat 41.4089 --lon -75.6624
+
+  # Everything within 500 m of the office
+  ue locations near office --radius 500`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLocationsNear,
+}
+
+func init() {
+	LocationsNearCmd.Flags().Float64Var(&nearLat, "lat", 0, "Latitude of the point")
+	LocationsNearCmd.Flags().Float64Var(&nearLon, "lon", 0, "Longitude of the point")
+	LocationsNearCmd.Flags().Float64Var(&nearRadius, "radius", 0, "Radius in meters (default: nearest location only)")
+	LocationsNearCmd.Flags().IntVar(&nearLimit, "limit", 0, "Maximum number of locations to show")
+
+	LocationsCmd.AddCommand(LocationsNearCmd)
+}
+
+func runLocationsNear(cmd *cobra.Command, args []string) error {
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	lat, lon := nearLat, nearLon
+	origin := ""
+	if len(args) == 1 {
+		lat, lon, err = resolvePoint(registry, args[0])
+		if err != nil {
+			return err
+		}
+		if loc, err := registry.Find(args[0]); err == nil {
+			origin = loc.ID
+		}
+	} else if !cmd.Flags().Changed("lat") || !cmd.Flags().Changed("lon") {
+		return fmt.Errorf("a location, \"lat,lon\" or --lat and --lon is required")
+	}
+
+	var nearby []locations.NearbyLocation
+	for _, n := range registry.Near(lat, lon, nearRadius) {
+		if n.ID == origin {
+			continue
+		}
+		nearby = append(nearby, n)
+		if nearRadius <= 0 {
+			break
+		}
+	}
+	if nearLimit > 0 && len(nearby) > nearLimit {
+		nearby = nearby[:nearLimit]
+	}
+
+	if len(nearby) == 0 {
+		fmt.Println("No locations found.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 10, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "ID\tLABEL\tDISTANCE\tVISITS\tADDRESS")
+	for _, n := range nearby {
+		fmt.Fprintf(tw, "%s\t%s\t%.0f m\t%d\t%s\n", n.ID, n.Label, n.Distance, n.VisitCount, truncate(n.DisplayAddress(), 40))
+	}
+	return tw.Flush()
+}
+
+// resolvePoint parses "lat,lon" or looks up a saved location and returns its
+// coordinates.
+func resolvePoint(registry *locations.Registry, s string) (float64, float64, error) {
+	if latStr, lonStr, ok := strings.Cut(s, ","); ok {
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+		if latErr == nil && lonErr == nil {
+			return lat, lon, nil
+		}
+	}
+
+	loc, err := registry.Find(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return loc.AvgLat, loc.AvgLon, nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/analysis"
+	"uber-extractor/internal/format"
+	"uber-extractor/internal/locations"
+)
+
+var (
+	odMinTrips int
+	odLimit    int
+)
+
+var ODMatrixCmd = &cobra.Command{
+	Use:   "od-matrix",
+	Short: "Show trip counts and costs between pairs of locations",
+	Long: `Group completed trips by pickup and dropoff location and show, for each pair, the number of
+trips, total and median fare, and median duration, busiest pairs first. Trips are read from a
+JSON export or fetched, and matched against the location registry.`,
+	Example: `  # Your most travelled corridors over the last 90 days
+  ue od-matrix --last 90d --limit 10
+
+  # Pairs with at least 3 trips from an export, as CSV
+  ue od-matrix --trips trips.json --min-trips 3 -o csv > od.csv`,
+	Args: cobra.NoArgs,
+	RunE: runODMatrix,
+}
+
+func init() {
+	addTripSourceFlags(ODMatrixCmd)
+	addClusterFlags(ODMatrixCmd)
+	addReportOutputFlag(ODMatrixCmd)
+	ODMatrixCmd.Flags().IntVar(&odMinTrips, "min-trips", 1, "Only show pairs with at least this many trips")
+	ODMatrixCmd.Flags().IntVar(&odLimit, "limit", 0, "Maximum number of pairs to show")
+}
+
+func runODMatrix(cmd *cobra.Command, args []string) error {
+	outputFormat, err := parseReportOutput()
+	if err != nil {
+		return err
+	}
+
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	tripList, err := loadLocatedTrips(context.Background(), registry)
+	if err != nil {
+		return err
+	}
+
+	var pairs []analysis.ODPair
+	for _, pair := range analysis.ODMatrix(registry, tripList) {
+		if pair.Trips >= odMinTrips {
+			pairs = append(pairs, pair)
+		}
+	}
+	if odLimit > 0 && len(pairs) > odLimit {
+		pairs = pairs[:odLimit]
+	}
+
+	if outputFormat == reportTable && len(pairs) == 0 {
+		fmt.Println("No trips between saved locations.")
+		return nil
+	}
+
+	var header []string
+	var rows [][]string
+	if outputFormat == reportCSV {
+		header = []string{"PickupLocationID", "PickupLabel", "DropoffLocationID", "DropoffLabel", "Trips", "Currency", "TotalFare", "MedianFare", "MedianDurationMinutes"}
+		for _, p := range pairs {
+			rows = append(rows, []string{
+				p.PickupID,
+				p.PickupLabel,
+				p.DropoffID,
+				p.DropoffLabel,
+				strconv.Itoa(p.Trips),
+				p.Currency,
+				strconv.FormatFloat(p.TotalFare, 'f', 2, 64),
+				strconv.FormatFloat(p.MedianFare, 'f', 2, 64),
+				strconv.FormatFloat(p.MedianDuration, 'f', 0, 64),
+			})
+		}
+	} else {
+		header = []string{"FROM", "TO", "TRIPS", "TOTAL", "MEDIAN FARE", "MEDIAN DURATION"}
+		for _, p := range pairs {
+			rows = append(rows, []string{
+				truncate(locationName(registry, p.PickupID), 30),
+				truncate(locationName(registry, p.DropoffID), 30),
+				strconv.Itoa(p.Trips),
+				fmt.Sprintf("%.2f %s", p.TotalFare, p.Currency),
+				fmt.Sprintf("%.2f %s", p.MedianFare, p.Currency),
+				format.FormatDuration(p.MedianDuration),
+			})
+		}
+	}
+
+	return writeReport(os.Stdout, outputFormat, header, rows, pairs)
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/geocode"
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/transform"
+	"uber-extractor/internal/trips"
+)
+
+var applyRecluster bool
+
+var LocationsReclusterCmd = &cobra.Command{
+	Use:   "recluster",
+	Short: "Rebuild the location registry from trip history",
+	Long: `Replay trips from a JSON export or a fresh fetch through an empty location processor and
+show how the resulting registry differs from the current one. Custom location radii still apply
+while clustering, and locations that clearly correspond keep their IDs, labels and radius.
+Nothing is saved unless --apply is given.`,
+	Example: `  # Preview a rebuild from a local export with a wider threshold
+  ue locations recluster --trips trips.json --cluster-threshold 40
+
+  # Rebuild from the last year of trips with dbscan and save the result
+  ue locations recluster --last 365d --cluster-algo dbscan --apply`,
+	Args: cobra.NoArgs,
+	RunE: runLocationsRecluster,
+}
+
+func init() {
+	addTripSourceFlags(LocationsReclusterCmd)
+	addClusterFlags(LocationsReclusterCmd)
+	addGazetteerFlag(LocationsReclusterCmd)
+	LocationsReclusterCmd.Flags().BoolVar(&applyRecluster, "apply", false, "Save the rebuilt registry (and update the --trips file)")
+
+	LocationsCmd.AddCommand(LocationsReclusterCmd)
+}
+
+func runLocationsRecluster(cmd *cobra.Command, args []string) error {
+	clusterConfig, err := parseClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	gazetteer, err := loadGazetteer()
+	if err != nil {
+		return err
+	}
+
+	tripList, err := loadTrips(context.Background())
+	if err != nil {
+		return err
+	}
+
+	result, err := reclusterRegistry(tripList, clusterConfig, gazetteer)
+	if err != nil {
+		return err
+	}
+
+	for i := range tripList {
+		trip := &tripList[i]
+		trip.PickupLocationID = result.IDs[trip.PickupLocationID]
+		trip.DropoffLocationID = result.IDs[trip.DropoffLocationID]
+		trip.PickupLabel = result.Registry.Label(trip.PickupLocationID)
+		trip.DropoffLabel = result.Registry.Label(trip.DropoffLocationID)
+	}
+
+	printReclusterDiff(result.Changes)
+
+	if !applyRecluster {
+		fmt.Println("\nDry run: use --apply to save the rebuilt registry.")
+		return nil
+	}
+
+	if err := saveTripsFile(tripsFile, tripList); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nSaved %d locations.\n", len(result.Registry.Locations))
+	return nil
+}
+
+// reclusterRegistry replays tripList through an empty processor and
+// reconciles the result with the current registry, saving it with --apply.
+// The registry lock is held only from loading the registry to saving it.
+func reclusterRegistry(tripList []trips.Trip, clusterConfig locations.Config, gazetteer *geocode.Gazetteer) (*locations.ReclusterResult, error) {
+	unlock, err := locations.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current, err := locations.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	lp := locations.NewProcessorWithConfig(nil, clusterConfig)
+	lp.SeedRadii(current)
+	transform.AssignLocations(tripList, lp)
+
+	if gazetteer != nil {
+		transform.GeocodeTrips(tripList, gazetteer)
+		transform.GeocodeLocations(lp.Registry(), gazetteer)
+	}
+
+	result := locations.Reconcile(current, lp.Registry(), clusterConfig.Threshold)
+
+	if applyRecluster {
+		if err := locations.Save(result.Registry); err != nil {
+			return nil, fmt.Errorf("failed to save locations: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func printReclusterDiff(changes []locations.Change) {
+	markers := map[locations.ChangeKind]string{
+		locations.ChangeKept:    "=",
+		locations.ChangeAdded:   "+",
+		locations.ChangeRemoved: "-",
+	}
+
+	counts := make(map[locations.ChangeKind]int)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tID\tLABEL\tVISITS\tMOVED\tADDRESS")
+	for _, c := range changes {
+		counts[c.Kind]++
+
+		visits := fmt.Sprintf("%d", c.NewVisits)
+		moved := ""
+		switch c.Kind {
+		case locations.ChangeKept:
+			visits = fmt.Sprintf("%d -> %d", c.OldVisits, c.NewVisits)
+			moved = fmt.Sprintf("%.0f m", c.Moved)
+		case locations.ChangeRemoved:
+			visits = fmt.Sprintf("%d", c.OldVisits)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			markers[c.Kind],
+			c.ID,
+			c.Label,
+			visits,
+			moved,
+			truncate(c.Address, 40),
+		)
+	}
+	tw.Flush()
+
+	fmt.Printf("\nKept: %d, added: %d, removed: %d\n", counts[locations.ChangeKept], counts[locations.ChangeAdded], counts[locations.ChangeRemoved])
+}
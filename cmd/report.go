@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	reportTable = "table"
+	reportCSV   = "csv"
+	reportJSON  = "json"
+)
+
+var reportOutput string
+
+func addReportOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&reportOutput, "output", "o", reportTable, "Output format: table, csv, json")
+}
+
+func parseReportOutput() (string, error) {
+	switch strings.ToLower(reportOutput) {
+	case reportTable, "":
+		return reportTable, nil
+	case reportCSV:
+		return reportCSV, nil
+	case reportJSON:
+		return reportJSON, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q: expected table, csv or json", reportOutput)
+	}
+}
+
+// writeReport writes rows under header as an aligned table or CSV, or data
+// as indented JSON.
+func writeReport(w io.Writer, outputFormat string, header []string, rows [][]string, data any) error {
+	switch outputFormat {
+	case reportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	case reportCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
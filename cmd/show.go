@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"uber-extractor/internal/analysis"
+	"uber-extractor/internal/geocode"
+	"uber-extractor/internal/locations"
+)
+
+const (
+	showTimeLayout    = "2006-01-02 15:04"
+	showMaxCompanions = 5
+	showMaxVisits     = 10
+)
+
+var LocationsShowCmd = &cobra.Command{
+	Use:   "show <location>",
+	Short: "Show details of a location",
+	Long: `Show all address variants, coordinates, visit count, first/last seen and a map link for a
+location. With trips from a JSON export or a fetch, also list the trips that started or ended
+there, the most common places travelled to and from, and the total spend.`,
+	Example: `  # Show a location and its most recent visits
+  ue locations show office
+
+  # Include trips, companions and spend from an export
+  ue locations show loc-3 --trips trips.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLocationsShow,
+}
+
+func init() {
+	addTripSourceFlags(LocationsShowCmd)
+	addClusterFlags(LocationsShowCmd)
+
+	LocationsCmd.AddCommand(LocationsShowCmd)
+}
+
+func runLocationsShow(cmd *cobra.Command, args []string) error {
+	registry, err := locations.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	found, err := registry.Find(args[0])
+	if err != nil {
+		return err
+	}
+	loc := *found
+
+	printLocationDetails(loc)
+
+	if !hasTripSource() {
+		printRecentVisits(loc)
+		return nil
+	}
+
+	tripList, err := loadLocatedTrips(context.Background(), registry)
+	if err != nil {
+		return err
+	}
+
+	printLocationActivity(registry, analysis.ActivityAt(registry, loc.ID, tripList))
+	return nil
+}
+
+func printLocationDetails(loc locations.Location) {
+	radius := fmt.Sprintf("%.0f m", loc.Radius)
+	if loc.Radius == 0 {
+		radius = "default"
+	}
+
+	fmt.Printf("ID:           %s\n", loc.ID)
+	if loc.Label != "" {
+		fmt.Printf("Label:        %s\n", loc.Label)
+	}
+	fmt.Printf("Address:      %s\n", loc.CanonicalAddress)
+	for i, variant := range loc.AddressVariants {
+		if i == 0 {
+			fmt.Printf("Variants:     %s\n", variant)
+		} else {
+			fmt.Printf("              %s\n", variant)
+		}
+	}
+	if place := (geocode.Place{Neighbourhood: loc.Neighbourhood, City: loc.City, Country: loc.Country}); !place.IsZero() {
+		fmt.Printf("Place:        %s\n", place)
+	}
+	fmt.Printf("Coordinates:  %.6f, %.6f\n", loc.AvgLat, loc.AvgLon)
+	fmt.Printf("Radius:       %s\n", radius)
+	fmt.Printf("Visits:       %d\n", loc.VisitCount)
+	fmt.Printf("First seen:   %s\n", showTime(loc.FirstSeen))
+	fmt.Printf("Last seen:    %s\n", showTime(loc.LastSeen))
+	fmt.Printf("Map:          %s\n", loc.MapURL())
+}
+
+func showTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(showTimeLayout)
+}
+
+func printRecentVisits(loc locations.Location) {
+	if len(loc.Visits) == 0 {
+		return
+	}
+
+	fmt.Printf("\nRecent visits:\n")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tROLE\tTRIP")
+	visits := loc.Visits[max(0, len(loc.Visits)-showMaxVisits):]
+	for i := len(visits) - 1; i >= 0; i-- {
+		v := visits[i]
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Time.Format(showTimeLayout), v.Role, v.TripUUID)
+	}
+	tw.Flush()
+
+	fmt.Printf("\nPass --trips or --last to include fares and destinations.\n")
+}
+
+func printLocationActivity(registry *locations.Registry, activity analysis.LocationActivity) {
+	if len(activity.Trips) == 0 {
+		fmt.Printf("\nNo trips started or ended here.\n")
+		return
+	}
+
+	fmt.Printf("\nTrips (%d):\n", len(activity.Trips))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tROLE\tFARE\tOTHER END")
+	for _, lt := range activity.Trips {
+		fmt.Fprintf(tw, "%s\t%s\t%.2f %s\t%s\n",
+			lt.Trip.BeginTime.Format(showTimeLayout),
+			lt.Role,
+			lt.Trip.Fare,
+			lt.Trip.Currency,
+			truncate(locationName(registry, lt.OtherID), 40),
+		)
+	}
+	tw.Flush()
+
+	if len(activity.Companions) > 0 {
+		fmt.Printf("\nMost common companion locations:\n")
+		tw = tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+		for _, c := range activity.Companions[:min(showMaxCompanions, len(activity.Companions))] {
+			fmt.Fprintf(tw, "  %s\t%d trips\n", truncate(locationName(registry, c.ID), 40), c.Count)
+		}
+		tw.Flush()
+	}
+
+	fmt.Println()
+	printSpend("Spend from here:", activity.SpendFrom)
+	printSpend("Spend to here:  ", activity.SpendTo)
+}
+
+func printSpend(title string, spend map[string]float64) {
+	for _, currency := range slices.Sorted(maps.Keys(spend)) {
+		fmt.Printf("%s %.2f %s\n", title, spend[currency], currency)
+	}
+}
+
+// locationName returns the label or ID of a location, or its address when it
+// has no label, for display next to trips.
+func locationName(registry *locations.Registry, id string) string {
+	loc, err := registry.Find(id)
+	if err != nil {
+		return id
+	}
+	if loc.Label != "" {
+		return loc.Label
+	}
+	return loc.ID + " " + loc.DisplayAddress()
+}
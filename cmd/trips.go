@@ -7,13 +7,16 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"uber-extractor/internal/analysis"
 	"uber-extractor/internal/auth"
 	"uber-extractor/internal/datetime"
 	"uber-extractor/internal/format"
+	"uber-extractor/internal/geocode"
 	"uber-extractor/internal/locations"
 	"uber-extractor/internal/parser"
 	"uber-extractor/internal/transform"
@@ -28,6 +31,24 @@ var (
 	output     string
 	summary    bool
 
+	csvColumns   []string
+	csvDelimiter string
+	csvLocale    string
+	csvBOM       bool
+
+	expenseAccount string
+	assetAccount   string
+	currency       string
+
+	clusterThreshold float64
+	clusterAlgo      string
+	addressLocale    string
+	matchConfidence  float64
+	nearPoint        string
+	nearTripRadius   float64
+	nearEnd          string
+	gazetteerPath    string
+
 	subtitleRegex = regexp.MustCompile(`([A-Za-z]+ \d+) • (\d+:\d+ [AP]M)`)
 )
 
@@ -43,15 +64,48 @@ var TripsCmd = &cobra.Command{
   ue trips --from 2024-01-01 --to 2024-01-31 --output csv
 
   # Show summary without fetching full details
-  ue trips --last 30d --summary`,
+  ue trips --last 30d --summary
+
+  # Export an Excel workbook with trips, monthly summary and locations sheets
+  ue trips --last 90d -o xlsx > trips.xlsx
+
+  # Write completed trips as a plain-text accounting journal
+  ue trips --last 30d -o beancount --expense-account Expenses:Transport:Taxi > transport.beancount
+
+  # Export a bank statement for GnuCash or Money-style tools
+  ue trips --last 30d -o ofx > uber.ofx
+
+  # Overlay trips on a calendar
+  ue trips --last 30d -o ics > trips.ics
+
+  # Cluster airports and malls with a wider radius, choosing clusters offline
+  ue trips --last 90d --cluster-threshold 60 --cluster-algo dbscan
+
+  # Only trips that ended within 500 m of the office
+  ue trips --last 90d --near office --near-radius 500 --near-end dropoff
+
+  # Export selected columns for Excel in a pt-BR locale
+  ue trips --last 30d -o csv --columns uuid,beginTime,fare,pickupLocationID --delimiter ';' --locale pt-BR --bom`,
 }
 
 func init() {
 	TripsCmd.Flags().StringVar(&fromDate, "from", "", "Start date in YYYY-MM-DD format")
 	TripsCmd.Flags().StringVar(&toDate, "to", "", "End date in YYYY-MM-DD format")
 	TripsCmd.Flags().StringVar(&lastPeriod, "last", "", "Period in days (e.g., 7d, 3d, 30d)")
-	TripsCmd.Flags().StringVarP(&output, "output", "o", "json", "Output format: json, csv (default: json)")
+	TripsCmd.Flags().StringVarP(&output, "output", "o", "json", "Output format: json, csv, xlsx, ledger, beancount, ofx, qif, ics (default: json)")
 	TripsCmd.Flags().BoolVar(&summary, "summary", false, "Show summary without fetching details")
+	TripsCmd.Flags().StringSliceVar(&csvColumns, "columns", nil, "Comma-separated CSV columns (available: "+strings.Join(format.CSVColumnNames(), ", ")+")")
+	TripsCmd.Flags().StringVar(&csvDelimiter, "delimiter", "", "CSV field delimiter, e.g. ';' or 'tab' (default: ',')")
+	TripsCmd.Flags().StringVar(&csvLocale, "locale", "", "Locale for number formatting, e.g. en-US, pt-BR")
+	TripsCmd.Flags().BoolVar(&csvBOM, "bom", false, "Prefix CSV output with a UTF-8 byte order mark")
+	TripsCmd.Flags().StringVar(&expenseAccount, "expense-account", format.DefaultExpenseAccount, "Expense account for ledger/beancount output")
+	TripsCmd.Flags().StringVar(&assetAccount, "asset-account", format.DefaultAssetAccount, "Asset account for ledger/beancount output")
+	TripsCmd.Flags().StringVar(&currency, "currency", format.DefaultCurrency, "Currency for fares without a currency symbol")
+	addClusterFlags(TripsCmd)
+	addGazetteerFlag(TripsCmd)
+	TripsCmd.Flags().StringVar(&nearPoint, "near", "", "Only output trips near a saved location or \"lat,lon\"")
+	TripsCmd.Flags().Float64Var(&nearTripRadius, "near-radius", 500, "Radius in meters for --near")
+	TripsCmd.Flags().StringVar(&nearEnd, "near-end", "any", "Trip end checked by --near: pickup, dropoff, any")
 }
 
 func runTrips(cmd *cobra.Command, args []string) error {
@@ -65,6 +119,27 @@ func runTrips(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	delimiter, err := format.ParseDelimiter(csvDelimiter)
+	if err != nil {
+		return err
+	}
+
+	clusterConfig, err := parseClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	opts := format.Options{
+		Columns:   csvColumns,
+		Delimiter: delimiter,
+		Locale:    csvLocale,
+		BOM:       csvBOM,
+
+		ExpenseAccount: expenseAccount,
+		AssetAccount:   assetAccount,
+		Currency:       currency,
+	}
+
 	client := uberapi.NewClient(creds.Cookie)
 	ctx := context.Background()
 
@@ -72,7 +147,7 @@ func runTrips(cmd *cobra.Command, args []string) error {
 		return runSummary(ctx, client, startTime, endTime)
 	}
 
-	return runFetch(ctx, client, startTime, endTime)
+	return runFetch(ctx, client, startTime, endTime, opts, clusterConfig)
 }
 
 func runSummary(ctx context.Context, client *uberapi.Client, start, end time.Time) error {
@@ -113,17 +188,226 @@ func runSummary(ctx context.Context, client *uberapi.Client, start, end time.Tim
 	return nil
 }
 
-func runFetch(ctx context.Context, client *uberapi.Client, start, end time.Time) error {
-	slog.Info("Starting trip fetch", "date_range", fmt.Sprintf("%s to %s", start.Format("2006-01-02"), end.Format("2006-01-02")))
+func runFetch(ctx context.Context, client *uberapi.Client, start, end time.Time, opts format.Options, clusterConfig locations.Config) error {
+	gazetteer, err := loadGazetteer()
+	if err != nil {
+		return err
+	}
+
+	allTrips, err := fetchTrips(ctx, client, start, end)
+	if err != nil {
+		return err
+	}
+
+	registry, err := recordTrips(allTrips, clusterConfig, gazetteer)
+	if err != nil {
+		return err
+	}
+
+	opts.Registry = registry
+	f, err := format.GetFormatter(output, opts)
+	if err != nil {
+		return err
+	}
+
+	filter, err := parseNearFilter(registry)
+	if err != nil {
+		return err
+	}
+
+	if filter != nil {
+		allTrips = filter(allTrips)
+		slog.Info("Filtered trips", "near", nearPoint, "radius_m", nearTripRadius, "count", len(allTrips))
+	}
+
+	slog.Info("Formatting output", "format", output, "destination", "stdout")
+
+	return f.Format(os.Stdout, allTrips)
+}
+
+// recordTrips assigns fetched trips to locations and saves the registry when
+// that recorded new visits or places. The registry lock is held only from
+// loading the registry to saving it, not while fetching or formatting.
+func recordTrips(tripList []trips.Trip, clusterConfig locations.Config, gazetteer *geocode.Gazetteer) (*locations.Registry, error) {
+	unlock, err := locations.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
 	registry, err := locations.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load locations: %w", err)
+		return nil, fmt.Errorf("failed to load locations: %w", err)
 	}
 
 	slog.Info("Locations loaded", "count", len(registry.Locations))
 
-	lp := locations.NewProcessor(registry)
+	lp := locations.NewProcessorWithConfig(registry, clusterConfig)
+
+	slog.Info("Clustering locations", "algorithm", clusterConfig.Algorithm, "threshold_m", clusterConfig.Threshold)
+	transform.AssignLocations(tripList, lp)
+	analysis.TagCommutes(lp.Registry(), tripList, time.Local, analysis.DefaultCommuteMinTrips)
+
+	geocoded := 0
+	if gazetteer != nil {
+		transform.GeocodeTrips(tripList, gazetteer)
+		geocoded = transform.GeocodeLocations(lp.Registry(), gazetteer)
+	}
+
+	stats := lp.Stats()
+	slog.Info("Visits ingested", "new", stats.Recorded, "already_recorded", stats.Skipped)
+
+	if stats.Recorded == 0 && geocoded == 0 {
+		slog.Info("Locations unchanged")
+	} else if err := locations.Save(lp.Registry()); err != nil {
+		slog.Warn("Failed to save locations", "error", err)
+	} else {
+		configDir, err := auth.GetConfigDir()
+		if err != nil {
+			slog.Info("Locations saved", "count", len(lp.Registry().Locations))
+		} else {
+			path := filepath.Join(configDir, "locations.json")
+			slog.Info("Locations saved", "count", len(lp.Registry().Locations), "path", path)
+		}
+	}
+
+	return lp.Registry(), nil
+}
+
+func parseClusterConfig() (locations.Config, error) {
+	algorithm, err := locations.ParseAlgorithm(clusterAlgo)
+	if err != nil {
+		return locations.Config{}, err
+	}
+
+	if clusterThreshold <= 0 {
+		return locations.Config{}, fmt.Errorf("--cluster-threshold must be positive")
+	}
+
+	if err := locations.LoadAbbreviations(); err != nil {
+		return locations.Config{}, err
+	}
+
+	if _, err := locations.GetNormalizer(addressLocale); err != nil {
+		return locations.Config{}, err
+	}
+
+	if matchConfidence <= 0 {
+		return locations.Config{}, fmt.Errorf("--match-confidence must be positive")
+	}
+
+	return locations.Config{
+		Threshold:     clusterThreshold,
+		Algorithm:     algorithm,
+		Locale:        addressLocale,
+		MinConfidence: matchConfidence,
+	}, nil
+}
+
+// parseNearFilter returns the --near trip filter, or nil when it is not set.
+func parseNearFilter(registry *locations.Registry) (func([]trips.Trip) []trips.Trip, error) {
+	if nearPoint == "" {
+		return nil, nil
+	}
+
+	lat, lon, err := resolvePoint(registry, nearPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := locations.ParseVisitRole(nearEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if nearTripRadius <= 0 {
+		return nil, fmt.Errorf("--near-radius must be positive")
+	}
+
+	return func(tripList []trips.Trip) []trips.Trip {
+		return analysis.TripsNear(tripList, lat, lon, nearTripRadius, role)
+	}, nil
+}
+
+// loadGazetteer loads the --gazetteer file, or the default one in the config
+// directory. It returns nil when no gazetteer is available.
+func loadGazetteer() (*geocode.Gazetteer, error) {
+	if gazetteerPath != "" {
+		return geocode.Load(gazetteerPath)
+	}
+	return geocode.LoadDefault()
+}
+
+func addGazetteerFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&gazetteerPath, "gazetteer", "", "Offline gazetteer (GeoNames dump or CSV) for city/neighbourhood/country (default: ~/.ue/gazetteer.csv if present)")
+}
+
+func addClusterFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64Var(&clusterThreshold, "cluster-threshold", locations.DefaultThreshold, "Distance in meters within which trips are clustered into the same location")
+	cmd.Flags().StringVar(&clusterAlgo, "cluster-algo", string(locations.AlgorithmGreedy), "Location clustering algorithm: greedy, dbscan")
+	cmd.Flags().StringVar(&addressLocale, "address-locale", locations.LocaleAuto, "Locale for address normalization: auto, "+strings.Join(locations.NormalizerLocales(), ", "))
+	cmd.Flags().Float64Var(&matchConfidence, "match-confidence", locations.DefaultMinConfidence, "Minimum fuzzy address match confidence (0-1) to merge nearby trips; above 1 disables fuzzy matching")
+}
+
+// loadTrips reads trips from the JSON export given with --trips, or fetches
+// them from Uber for the --from/--to or --last date range.
+func loadTrips(ctx context.Context) ([]trips.Trip, error) {
+	if tripsFile != "" {
+		tripList, err := trips.LoadFile(tripsFile)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Trips loaded", "path", tripsFile, "count", len(tripList))
+		return tripList, nil
+	}
+
+	creds, err := auth.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	startTime, endTime, err := datetime.ParseDateRange(fromDate, toDate, lastPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchTrips(ctx, uberapi.NewClient(creds.Cookie), startTime, endTime)
+}
+
+// hasTripSource reports whether --trips or a date range was given.
+func hasTripSource() bool {
+	return tripsFile != "" || fromDate != "" || toDate != "" || lastPeriod != ""
+}
+
+// loadLocatedTrips loads trips like loadTrips. Fetched trips have no location
+// IDs yet, so they are matched against registry with the cluster flags; trip
+// ends that match no saved location are left unassigned.
+func loadLocatedTrips(ctx context.Context, registry *locations.Registry) ([]trips.Trip, error) {
+	clusterConfig, err := parseClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tripList, err := loadTrips(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if tripsFile == "" {
+		transform.LocateTrips(tripList, locations.NewProcessorWithConfig(registry, clusterConfig))
+	}
+	return tripList, nil
+}
+
+func addTripSourceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&tripsFile, "trips", "", "JSON trips export to read instead of fetching from Uber")
+	cmd.Flags().StringVar(&fromDate, "from", "", "Start date in YYYY-MM-DD format")
+	cmd.Flags().StringVar(&toDate, "to", "", "End date in YYYY-MM-DD format")
+	cmd.Flags().StringVar(&lastPeriod, "last", "", "Period in days (e.g., 7d, 3d, 30d)")
+}
+
+func fetchTrips(ctx context.Context, client *uberapi.Client, start, end time.Time) ([]trips.Trip, error) {
+	slog.Info("Starting trip fetch", "date_range", fmt.Sprintf("%s to %s", start.Format("2006-01-02"), end.Format("2006-01-02")))
 
 	var allTrips []trips.Trip
 	pageToken := ""
@@ -134,7 +418,7 @@ func runFetch(ctx context.Context, client *uberapi.Client, start, end time.Time)
 
 		activities, nextPageToken, err := client.GetActivities(ctx, start.Unix()*1000, end.Unix()*1000, pageToken)
 		if err != nil {
-			return fmt.Errorf("failed to fetch activities: %w", err)
+			return nil, fmt.Errorf("failed to fetch activities: %w", err)
 		}
 
 		activitiesList := activities.Data.Activities.Past.Activities
@@ -158,7 +442,7 @@ func runFetch(ctx context.Context, client *uberapi.Client, start, end time.Time)
 
 			slog.Debug("Transforming trip", "uuid", activity.UUID)
 
-			trip, err := transform.ProcessTrip(tripResponse, lp)
+			trip, err := transform.ProcessTrip(tripResponse)
 			if err != nil {
 				slog.Warn("Failed to process trip", "uuid", activity.UUID, "error", err)
 				continue
@@ -177,28 +461,9 @@ func runFetch(ctx context.Context, client *uberapi.Client, start, end time.Time)
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	slog.Info("Trips processed successfully", "total", len(allTrips), "pages", pageCount)
+	slog.Info("Trips fetched successfully", "total", len(allTrips), "pages", pageCount)
 
-	if err := locations.Save(lp.Registry()); err != nil {
-		slog.Warn("Failed to save locations", "error", err)
-	} else {
-		configDir, err := auth.GetConfigDir()
-		if err != nil {
-			slog.Info("Locations saved", "count", len(lp.Registry().Locations))
-		} else {
-			path := filepath.Join(configDir, "locations.json")
-			slog.Info("Locations saved", "count", len(lp.Registry().Locations), "path", path)
-		}
-	}
-
-	slog.Info("Formatting output", "format", output, "destination", "stdout")
-
-	f, err := format.GetFormatter(output)
-	if err != nil {
-		return err
-	}
-
-	return f.Format(os.Stdout, allTrips)
+	return allTrips, nil
 }
 
 func parseSubtitle(subtitle string) []string {
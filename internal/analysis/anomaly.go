@@ -0,0 +1,150 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+type AnomalyKind string
+
+const (
+	AnomalyFarePerKm        AnomalyKind = "fare-per-km"
+	AnomalyFarePerMinute    AnomalyKind = "fare-per-minute"
+	AnomalyNegativeDuration AnomalyKind = "negative-duration"
+	AnomalyEndBeforeBegin   AnomalyKind = "end-before-begin"
+	AnomalyZeroDistance     AnomalyKind = "zero-distance"
+)
+
+const (
+	// DefaultAnomalyThreshold is how many times above or below the median
+	// fare rate a trip has to be to be flagged.
+	DefaultAnomalyThreshold = 2.0
+
+	// Fare rates are only compared within groups of at least this many
+	// trips, in buckets of anomalyHourBucket hours of local departure time.
+	minAnomalyGroup   = 3
+	anomalyHourBucket = 3
+)
+
+// Anomaly is a trip with a suspicious fare or impossible data. For fare rate
+// anomalies Value is the trip's fare per km or minute, Median the median of
+// comparable trips and Ratio their quotient.
+type Anomaly struct {
+	Trip   trips.Trip  `json:"trip"`
+	Kind   AnomalyKind `json:"kind"`
+	Value  float64     `json:"value,omitempty"`
+	Median float64     `json:"median,omitempty"`
+	Ratio  float64     `json:"ratio,omitempty"`
+	Reason string      `json:"reason"`
+}
+
+type rateKey struct {
+	pickup   string
+	dropoff  string
+	vehicle  string
+	currency string
+	bucket   int
+}
+
+type tripRate struct {
+	index int
+	value float64
+}
+
+// FindAnomalies flags trips with impossible data and completed trips whose
+// fare per km or per minute is at least threshold times above or below the
+// median of trips between the same locations, in the same vehicle type and
+// at a similar local hour in tz. Anomalies are ordered by trip time.
+func FindAnomalies(registry *locations.Registry, tripList []trips.Trip, tz *time.Location, threshold float64) []Anomaly {
+	var anomalies []Anomaly
+	perKm := make(map[rateKey][]tripRate)
+	perMinute := make(map[rateKey][]tripRate)
+
+	for i, trip := range tripList {
+		if trip.Duration < 0 {
+			anomalies = append(anomalies, Anomaly{Trip: trip, Kind: AnomalyNegativeDuration, Value: trip.Duration,
+				Reason: fmt.Sprintf("duration is %.0f minutes", trip.Duration)})
+		}
+		if !trip.BeginTime.IsZero() && !trip.EndTime.IsZero() && trip.EndTime.Before(trip.BeginTime) {
+			anomalies = append(anomalies, Anomaly{Trip: trip, Kind: AnomalyEndBeforeBegin,
+				Reason: fmt.Sprintf("ended %s before it began", trip.BeginTime.Sub(trip.EndTime).Round(time.Minute))})
+		}
+
+		if trip.Status != trips.StatusCompleted || trip.Fare <= 0 {
+			continue
+		}
+		if trip.Distance == 0 {
+			anomalies = append(anomalies, Anomaly{Trip: trip, Kind: AnomalyZeroDistance,
+				Reason: fmt.Sprintf("fare of %.2f %s for a trip with no distance", trip.Fare, trip.Currency)})
+		}
+
+		k := rateKey{
+			pickup:   resolveID(registry, trip.PickupLocationID),
+			dropoff:  resolveID(registry, trip.DropoffLocationID),
+			vehicle:  trip.VehicleType,
+			currency: trip.Currency,
+			bucket:   trip.BeginTime.In(tz).Hour() / anomalyHourBucket,
+		}
+		if k.pickup == "" || k.dropoff == "" || trip.BeginTime.IsZero() {
+			continue
+		}
+		if trip.Distance > 0 {
+			perKm[k] = append(perKm[k], tripRate{index: i, value: trip.Fare / trip.Distance})
+		}
+		if trip.Duration > 0 {
+			perMinute[k] = append(perMinute[k], tripRate{index: i, value: trip.Fare / trip.Duration})
+		}
+	}
+
+	anomalies = append(anomalies, rateAnomalies(tripList, perKm, AnomalyFarePerKm, "km", threshold)...)
+	anomalies = append(anomalies, rateAnomalies(tripList, perMinute, AnomalyFarePerMinute, "minute", threshold)...)
+
+	slices.SortStableFunc(anomalies, func(a, b Anomaly) int {
+		return cmp.Or(
+			a.Trip.BeginTime.Compare(b.Trip.BeginTime),
+			cmp.Compare(a.Trip.UUID, b.Trip.UUID),
+			cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+	return anomalies
+}
+
+func rateAnomalies(tripList []trips.Trip, groups map[rateKey][]tripRate, kind AnomalyKind, unit string, threshold float64) []Anomaly {
+	var anomalies []Anomaly
+	for _, rates := range groups {
+		if len(rates) < minAnomalyGroup {
+			continue
+		}
+
+		values := make([]float64, len(rates))
+		for i, r := range rates {
+			values[i] = r.value
+		}
+		m := median(values)
+		if m <= 0 {
+			continue
+		}
+
+		for _, r := range rates {
+			ratio := r.value / m
+			if ratio < threshold && ratio > 1/threshold {
+				continue
+			}
+			trip := tripList[r.index]
+			anomalies = append(anomalies, Anomaly{
+				Trip:   trip,
+				Kind:   kind,
+				Value:  r.value,
+				Median: m,
+				Ratio:  ratio,
+				Reason: fmt.Sprintf("%.2f %s per %s is %.1f× the median of %.2f over %d similar trips", r.value, trip.Currency, unit, ratio, m, len(rates)),
+			})
+		}
+	}
+	return anomalies
+}
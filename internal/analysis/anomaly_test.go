@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestFindAnomalies(t *testing.T) {
+	registry := &locations.Registry{Locations: []locations.Location{{ID: "loc-1"}, {ID: "loc-2"}}, NextID: 3}
+
+	begin := func(day int) time.Time { return time.Date(2024, 9, day, 8, 30, 0, 0, time.UTC) }
+	trip := func(uuid string, day int, fare, distance, duration float64) trips.Trip {
+		return trips.Trip{
+			UUID:              uuid,
+			Status:            trips.StatusCompleted,
+			BeginTime:         begin(day),
+			EndTime:           begin(day).Add(time.Duration(duration) * time.Minute),
+			Fare:              fare,
+			Currency:          "BRL",
+			VehicleType:       "UberX",
+			Distance:          distance,
+			Duration:          duration,
+			PickupLocationID:  "loc-1",
+			DropoffLocationID: "loc-2",
+		}
+	}
+
+	surge := trip("surge", 5, 60, 10, 20)
+	evening := trip("evening", 6, 60, 10, 20)
+	evening.BeginTime = evening.BeginTime.Add(10 * time.Hour)
+	evening.EndTime = evening.EndTime.Add(10 * time.Hour)
+	backwards := trip("backwards", 7, 20, 10, 20)
+	backwards.EndTime = backwards.BeginTime.Add(-time.Hour)
+	negative := trip("negative", 8, 20, 10, -5)
+	negative.EndTime = time.Time{}
+
+	tripList := []trips.Trip{
+		trip("a", 1, 20, 10, 20),
+		trip("b", 2, 22, 10, 22),
+		trip("c", 3, 21, 10, 21),
+		trip("d", 4, 19, 10, 19),
+		surge,
+		evening,
+		backwards,
+		negative,
+		trip("nowhere", 9, 15, 0, 20),
+		{UUID: "fee", Status: trips.StatusCanceled, Fare: 5, BeginTime: begin(10)},
+	}
+
+	got := make(map[string][]AnomalyKind)
+	for _, a := range FindAnomalies(registry, tripList, time.UTC, DefaultAnomalyThreshold) {
+		got[a.Trip.UUID] = append(got[a.Trip.UUID], a.Kind)
+	}
+
+	want := map[string][]AnomalyKind{
+		"surge":     {AnomalyFarePerKm, AnomalyFarePerMinute},
+		"backwards": {AnomalyEndBeforeBegin},
+		"negative":  {AnomalyNegativeDuration},
+		"nowhere":   {AnomalyZeroDistance},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected anomalies %v, got %v", want, got)
+	}
+	for uuid, kinds := range want {
+		if len(got[uuid]) != len(kinds) {
+			t.Errorf("%s: expected %v, got %v", uuid, kinds, got[uuid])
+			continue
+		}
+		for i := range kinds {
+			if got[uuid][i] != kinds[i] {
+				t.Errorf("%s: expected %v, got %v", uuid, kinds, got[uuid])
+			}
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+type CommuteKind string
+
+const (
+	CommuteOutbound CommuteKind = "commute"
+	CommuteReturn   CommuteKind = "return-commute"
+	CommuteAdHoc    CommuteKind = "ad-hoc"
+)
+
+const (
+	// DefaultCommuteMinTrips is how many trips along the same pair of
+	// locations at a similar time it takes to call them a commute.
+	DefaultCommuteMinTrips = 4
+
+	// Departures belong to the same time window when each is at most
+	// commuteMaxGap minutes after the previous one and the window spans at
+	// most commuteMaxSpread minutes.
+	commuteMaxGap    = 45
+	commuteMaxSpread = 120
+
+	// A commute has to recur in at least this many different weeks.
+	commuteMinWeeks = 2
+
+	minutesPerDay = 24 * 60
+)
+
+// Commute is a recurring trip between two locations in a time window on
+// weekdays or on weekends. Departure is the median local departure time.
+type Commute struct {
+	Kind         CommuteKind `json:"kind"`
+	PickupID     string      `json:"pickupLocationID"`
+	PickupLabel  string      `json:"pickupLabel,omitempty"`
+	DropoffID    string      `json:"dropoffLocationID"`
+	DropoffLabel string      `json:"dropoffLabel,omitempty"`
+	Weekend      bool        `json:"weekend"`
+	Weekdays     []string    `json:"weekdays"`
+	Departure    string      `json:"departure"`
+	Trips        int         `json:"trips"`
+	PerWeek      float64     `json:"tripsPerWeek"`
+	Currency     string      `json:"currency"`
+	TotalFare    float64     `json:"totalFare"`
+	MonthlyCost  float64     `json:"monthlyCost"`
+	First        time.Time   `json:"first"`
+	Last         time.Time   `json:"last"`
+
+	minute int
+}
+
+type commuteKey struct {
+	pickup   string
+	dropoff  string
+	currency string
+	weekend  bool
+}
+
+type departure struct {
+	index  int
+	local  time.Time
+	minute int
+}
+
+// DetectCommutes finds commutes among the completed trips, comparing local
+// departure times in tz, and sets CommuteType on every completed trip. Of two
+// commutes in opposite directions, the one that departs earlier is the
+// commute and the other the return commute. Commutes are ordered by number
+// of trips, most frequent first.
+func DetectCommutes(registry *locations.Registry, tripList []trips.Trip, tz *time.Location, minTrips int) []Commute {
+	groups := make(map[commuteKey][]departure)
+	var keys []commuteKey
+
+	for i := range tripList {
+		trip := &tripList[i]
+		if trip.Status != trips.StatusCompleted {
+			continue
+		}
+		trip.CommuteType = string(CommuteAdHoc)
+
+		if trip.BeginTime.IsZero() {
+			continue
+		}
+		local := trip.BeginTime.In(tz)
+		k := commuteKey{
+			pickup:   resolveID(registry, trip.PickupLocationID),
+			dropoff:  resolveID(registry, trip.DropoffLocationID),
+			currency: trip.Currency,
+			weekend:  local.Weekday() == time.Saturday || local.Weekday() == time.Sunday,
+		}
+		if k.pickup == "" || k.dropoff == "" || k.pickup == k.dropoff {
+			continue
+		}
+
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], departure{index: i, local: local, minute: local.Hour()*60 + local.Minute()})
+	}
+
+	var commutes []Commute
+	var members [][]int
+	for _, k := range keys {
+		for _, window := range timeWindows(groups[k]) {
+			if len(window) < minTrips || distinctWeeks(window) < commuteMinWeeks {
+				continue
+			}
+			commutes = append(commutes, newCommute(registry, k, window, tripList))
+			indexes := make([]int, len(window))
+			for i, d := range window {
+				indexes[i] = d.index
+			}
+			members = append(members, indexes)
+		}
+	}
+
+	for i := range commutes {
+		commutes[i].Kind = CommuteOutbound
+		for _, other := range commutes {
+			if other.PickupID == commutes[i].DropoffID && other.DropoffID == commutes[i].PickupID &&
+				other.Weekend == commutes[i].Weekend && other.minute < commutes[i].minute {
+				commutes[i].Kind = CommuteReturn
+				break
+			}
+		}
+		for _, idx := range members[i] {
+			tripList[idx].CommuteType = string(commutes[i].Kind)
+		}
+	}
+
+	slices.SortStableFunc(commutes, func(a, b Commute) int {
+		return cmp.Or(cmp.Compare(b.Trips, a.Trips), cmp.Compare(a.minute, b.minute))
+	})
+	return commutes
+}
+
+// TagCommutes sets CommuteType on the completed trips in tripList from the
+// commutes found in the visit log of registry, so a trip is tagged against
+// every recorded trip and not only the ones in tripList. The trips must
+// already be recorded in registry; trips that are not are tagged ad-hoc.
+func TagCommutes(registry *locations.Registry, tripList []trips.Trip, tz *time.Location, minTrips int) {
+	history := visitTrips(registry)
+	DetectCommutes(registry, history, tz, minTrips)
+
+	kinds := make(map[string]string, len(history))
+	for _, trip := range history {
+		kinds[trip.UUID] = trip.CommuteType
+	}
+
+	for i := range tripList {
+		trip := &tripList[i]
+		if trip.Status != trips.StatusCompleted {
+			continue
+		}
+		trip.CommuteType = cmp.Or(kinds[trip.UUID], string(CommuteAdHoc))
+	}
+}
+
+// visitTrips rebuilds the recorded trips from the pickup and dropoff visits
+// of the registry's locations. Fares are not part of the visit log, so the
+// trips have none.
+func visitTrips(registry *locations.Registry) []trips.Trip {
+	var tripList []trips.Trip
+	byUUID := make(map[string]int)
+	for _, loc := range registry.Locations {
+		for _, visit := range loc.Visits {
+			if visit.TripUUID == "" {
+				continue
+			}
+			i, ok := byUUID[visit.TripUUID]
+			if !ok {
+				i = len(tripList)
+				byUUID[visit.TripUUID] = i
+				tripList = append(tripList, trips.Trip{UUID: visit.TripUUID, Status: trips.StatusCompleted})
+			}
+
+			switch visit.Role {
+			case locations.VisitPickup:
+				tripList[i].PickupLocationID = loc.ID
+				tripList[i].BeginTime = visit.Time
+			case locations.VisitDropoff:
+				tripList[i].DropoffLocationID = loc.ID
+				tripList[i].EndTime = visit.Time
+			}
+		}
+	}
+	return tripList
+}
+
+// timeWindows splits departures into windows of similar time of day. Times
+// of day wrap around midnight: the departures are ordered starting after the
+// widest gap between them, and those moved past midnight get minutes beyond
+// minutesPerDay, so 23:50 and 00:10 can share a window.
+func timeWindows(departures []departure) [][]departure {
+	if len(departures) == 0 {
+		return nil
+	}
+
+	sorted := slices.Clone(departures)
+	slices.SortStableFunc(sorted, func(a, b departure) int { return cmp.Compare(a.minute, b.minute) })
+
+	first, widest := 0, sorted[0].minute+minutesPerDay-sorted[len(sorted)-1].minute
+	for i := 1; i < len(sorted); i++ {
+		if gap := sorted[i].minute - sorted[i-1].minute; gap > widest {
+			first, widest = i, gap
+		}
+	}
+	sorted = slices.Concat(sorted[first:], sorted[:first])
+	for i := len(sorted) - first; i < len(sorted); i++ {
+		sorted[i].minute += minutesPerDay
+	}
+
+	var windows [][]departure
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i == len(sorted) ||
+			sorted[i].minute-sorted[i-1].minute > commuteMaxGap ||
+			sorted[i].minute-sorted[start].minute > commuteMaxSpread {
+			windows = append(windows, sorted[start:i])
+			start = i
+		}
+	}
+	return windows
+}
+
+func distinctWeeks(departures []departure) int {
+	weeks := make(map[[2]int]bool)
+	for _, d := range departures {
+		year, week := d.local.ISOWeek()
+		weeks[[2]int{year, week}] = true
+	}
+	return len(weeks)
+}
+
+func newCommute(registry *locations.Registry, k commuteKey, window []departure, tripList []trips.Trip) Commute {
+	c := Commute{
+		PickupID:     k.pickup,
+		PickupLabel:  registry.Label(k.pickup),
+		DropoffID:    k.dropoff,
+		DropoffLabel: registry.Label(k.dropoff),
+		Weekend:      k.weekend,
+		Trips:        len(window),
+		Currency:     k.currency,
+	}
+
+	var minutes []float64
+	var days [7]bool
+	for _, d := range window {
+		minutes = append(minutes, float64(d.minute))
+		days[d.local.Weekday()] = true
+		c.TotalFare += tripList[d.index].Fare
+
+		if c.First.IsZero() || d.local.Before(c.First) {
+			c.First = d.local
+		}
+		if d.local.After(c.Last) {
+			c.Last = d.local
+		}
+	}
+
+	// Monday first.
+	for i := 1; i <= 7; i++ {
+		if day := time.Weekday(i % 7); days[day] {
+			c.Weekdays = append(c.Weekdays, day.String()[:3])
+		}
+	}
+
+	c.minute = int(median(minutes)) % minutesPerDay
+	c.Departure = fmt.Sprintf("%02d:%02d", c.minute/60, c.minute%60)
+
+	weeks := max(1, c.Last.Sub(c.First).Hours()/(24*7))
+	c.PerWeek = float64(c.Trips) / weeks
+	c.MonthlyCost = c.TotalFare / float64(monthsSpanned(c.First, c.Last))
+	return c
+}
+
+// monthsSpanned counts the calendar months from first to last, inclusive.
+func monthsSpanned(first, last time.Time) int {
+	return (last.Year()-first.Year())*12 + int(last.Month()-first.Month()) + 1
+}
@@ -0,0 +1,167 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestDetectCommutes(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{
+			{ID: "loc-1", Label: "home"},
+			{ID: "loc-2", Label: "office"},
+			{ID: "loc-3"},
+		},
+		NextID: 4,
+	}
+
+	trip := func(uuid, pickup, dropoff string, begin time.Time, fare float64) trips.Trip {
+		return trips.Trip{UUID: uuid, Status: trips.StatusCompleted, Currency: "BRL", Fare: fare, BeginTime: begin, PickupLocationID: pickup, DropoffLocationID: dropoff}
+	}
+	// Mondays and Wednesdays in September 2024.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 9, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	var tripList []trips.Trip
+	for _, day := range []int{2, 4, 9, 11, 16} {
+		tripList = append(tripList,
+			trip("in", "loc-1", "loc-2", at(day, 8, day%3*10), 20),
+			trip("out", "loc-2", "loc-1", at(day, 18, 15), 25),
+		)
+	}
+	tripList = append(tripList,
+		trip("late", "loc-1", "loc-2", at(18, 14, 0), 30),
+		trip("shop", "loc-1", "loc-3", at(7, 10, 0), 12),
+		trips.Trip{UUID: "canceled", Status: trips.StatusCanceled, BeginTime: at(9, 8, 0)},
+	)
+
+	commutes := DetectCommutes(registry, tripList, time.UTC, DefaultCommuteMinTrips)
+	if len(commutes) != 2 {
+		t.Fatalf("expected 2 commutes, got %d: %+v", len(commutes), commutes)
+	}
+
+	morning, evening := commutes[0], commutes[1]
+	if morning.Kind != CommuteOutbound || morning.PickupLabel != "home" || morning.DropoffLabel != "office" {
+		t.Errorf("expected home -> office commute first, got %+v", morning)
+	}
+	if morning.Departure != "08:10" || morning.Trips != 5 {
+		t.Errorf("unexpected morning commute: %+v", morning)
+	}
+	if !slices.Equal(morning.Weekdays, []string{"Mon", "Wed"}) {
+		t.Errorf("expected Mon and Wed, got %v", morning.Weekdays)
+	}
+	if morning.MonthlyCost != 100 {
+		t.Errorf("expected monthly cost 100, got %v", morning.MonthlyCost)
+	}
+
+	if evening.Kind != CommuteReturn || evening.Departure != "18:15" {
+		t.Errorf("expected return commute at 18:15, got %+v", evening)
+	}
+
+	kinds := make(map[string][]string)
+	for _, trip := range tripList {
+		kinds[trip.UUID] = append(kinds[trip.UUID], trip.CommuteType)
+	}
+	if !slices.Equal(slices.Compact(kinds["in"]), []string{"commute"}) ||
+		!slices.Equal(slices.Compact(kinds["out"]), []string{"return-commute"}) {
+		t.Errorf("unexpected commute tags: %v", kinds)
+	}
+	for _, uuid := range []string{"late", "shop"} {
+		if kinds[uuid][0] != "ad-hoc" {
+			t.Errorf("expected %s to be ad-hoc, got %q", uuid, kinds[uuid][0])
+		}
+	}
+	if kinds["canceled"][0] != "" {
+		t.Errorf("expected canceled trip to stay untagged, got %q", kinds["canceled"][0])
+	}
+}
+
+func TestDetectCommutesNeedsSeveralWeeks(t *testing.T) {
+	registry := &locations.Registry{Locations: []locations.Location{{ID: "loc-1"}, {ID: "loc-2"}}, NextID: 3}
+
+	var tripList []trips.Trip
+	for day := 2; day <= 6; day++ {
+		tripList = append(tripList, trips.Trip{
+			Status:            trips.StatusCompleted,
+			BeginTime:         time.Date(2024, 9, day, 9, 0, 0, 0, time.UTC),
+			PickupLocationID:  "loc-1",
+			DropoffLocationID: "loc-2",
+		})
+	}
+
+	if commutes := DetectCommutes(registry, tripList, time.UTC, DefaultCommuteMinTrips); len(commutes) != 0 {
+		t.Errorf("expected no commutes within a single week, got %+v", commutes)
+	}
+}
+
+func TestDetectCommutesAcrossMidnight(t *testing.T) {
+	registry := &locations.Registry{Locations: []locations.Location{{ID: "loc-1"}, {ID: "loc-2"}}, NextID: 3}
+
+	var tripList []trips.Trip
+	for i, at := range []time.Time{
+		time.Date(2024, 9, 2, 23, 50, 0, 0, time.UTC),
+		time.Date(2024, 9, 4, 0, 10, 0, 0, time.UTC),
+		time.Date(2024, 9, 9, 23, 55, 0, 0, time.UTC),
+		time.Date(2024, 9, 11, 0, 5, 0, 0, time.UTC),
+		time.Date(2024, 9, 17, 0, 15, 0, 0, time.UTC),
+	} {
+		tripList = append(tripList, trips.Trip{
+			UUID:              fmt.Sprintf("trip-%d", i),
+			Status:            trips.StatusCompleted,
+			BeginTime:         at,
+			PickupLocationID:  "loc-1",
+			DropoffLocationID: "loc-2",
+		})
+	}
+
+	commutes := DetectCommutes(registry, tripList, time.UTC, DefaultCommuteMinTrips)
+	if len(commutes) != 1 || commutes[0].Trips != 5 {
+		t.Fatalf("expected one commute around midnight, got %+v", commutes)
+	}
+	if commutes[0].Departure != "00:05" {
+		t.Errorf("expected median departure 00:05, got %s", commutes[0].Departure)
+	}
+}
+
+func TestTagCommutes(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{{ID: "loc-1"}, {ID: "loc-2"}, {ID: "loc-3"}},
+		NextID:    4,
+	}
+
+	// Four weeks of Monday morning trips are already recorded.
+	for week := range 4 {
+		uuid := fmt.Sprintf("past-%d", week)
+		begin := time.Date(2024, 9, 2+7*week, 8, 0, 0, 0, time.UTC)
+		registry.Locations[0].Visits = append(registry.Locations[0].Visits, locations.Visit{TripUUID: uuid, Role: locations.VisitPickup, Time: begin})
+		registry.Locations[1].Visits = append(registry.Locations[1].Visits, locations.Visit{TripUUID: uuid, Role: locations.VisitDropoff, Time: begin.Add(20 * time.Minute)})
+	}
+	registry.Locations[0].Visits = append(registry.Locations[0].Visits, locations.Visit{TripUUID: "new", Role: locations.VisitPickup, Time: time.Date(2024, 9, 30, 8, 10, 0, 0, time.UTC)})
+	registry.Locations[1].Visits = append(registry.Locations[1].Visits, locations.Visit{TripUUID: "new", Role: locations.VisitDropoff, Time: time.Date(2024, 9, 30, 8, 30, 0, 0, time.UTC)})
+	registry.Locations[2].Visits = append(registry.Locations[2].Visits, locations.Visit{TripUUID: "errand", Role: locations.VisitPickup, Time: time.Date(2024, 9, 30, 8, 0, 0, 0, time.UTC)})
+
+	// Only the latest week was fetched.
+	tripList := []trips.Trip{
+		{UUID: "new", Status: trips.StatusCompleted, BeginTime: time.Date(2024, 9, 30, 8, 10, 0, 0, time.UTC), PickupLocationID: "loc-1", DropoffLocationID: "loc-2"},
+		{UUID: "errand", Status: trips.StatusCompleted, BeginTime: time.Date(2024, 9, 30, 8, 0, 0, 0, time.UTC), PickupLocationID: "loc-3"},
+		{UUID: "canceled", Status: trips.StatusCanceled},
+	}
+
+	TagCommutes(registry, tripList, time.UTC, DefaultCommuteMinTrips)
+
+	if tripList[0].CommuteType != string(CommuteOutbound) {
+		t.Errorf("expected the fetched trip to be tagged from the visit log, got %q", tripList[0].CommuteType)
+	}
+	if tripList[1].CommuteType != string(CommuteAdHoc) {
+		t.Errorf("expected errand to be ad-hoc, got %q", tripList[1].CommuteType)
+	}
+	if tripList[2].CommuteType != "" {
+		t.Errorf("expected canceled trip to stay untagged, got %q", tripList[2].CommuteType)
+	}
+}
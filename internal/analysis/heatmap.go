@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+// Heatmap holds a value per weekday and hour of the day, with Monday in the
+// first row.
+type Heatmap struct {
+	Cells [7][24]float64
+	Max   float64
+	Total float64
+}
+
+// Weekday returns the weekday of row i.
+func (h *Heatmap) Weekday(i int) time.Weekday {
+	return time.Weekday((i + 1) % 7)
+}
+
+// BuildHeatmap counts the completed trips by local weekday and hour of their
+// begin time in tz, or sums their fares when spend is true.
+func BuildHeatmap(tripList []trips.Trip, tz *time.Location, spend bool) Heatmap {
+	var h Heatmap
+	for _, trip := range tripList {
+		if trip.Status != trips.StatusCompleted || trip.BeginTime.IsZero() {
+			continue
+		}
+
+		local := trip.BeginTime.In(tz)
+		row := (int(local.Weekday()) + 6) % 7
+
+		value := 1.0
+		if spend {
+			value = trip.Fare
+		}
+		h.Cells[row][local.Hour()] += value
+		h.Total += value
+	}
+
+	for _, row := range h.Cells {
+		for _, v := range row {
+			h.Max = max(h.Max, v)
+		}
+	}
+	return h
+}
+
+// TripsAt returns the trips that started or ended at the location with the
+// given ID, resolving merged location IDs through the registry.
+func TripsAt(registry *locations.Registry, id string, tripList []trips.Trip) []trips.Trip {
+	var result []trips.Trip
+	for _, trip := range tripList {
+		if resolveID(registry, trip.PickupLocationID) == id || resolveID(registry, trip.DropoffLocationID) == id {
+			result = append(result, trip)
+		}
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestBuildHeatmap(t *testing.T) {
+	saoPaulo := time.FixedZone("BRT", -3*60*60)
+
+	completed := func(begin time.Time, fare float64) trips.Trip {
+		return trips.Trip{Status: trips.StatusCompleted, BeginTime: begin, Fare: fare}
+	}
+	tripList := []trips.Trip{
+		// Monday 11:30 UTC is Monday 08:30 in São Paulo.
+		completed(time.Date(2024, 9, 2, 11, 30, 0, 0, time.UTC), 20),
+		completed(time.Date(2024, 9, 9, 11, 45, 0, 0, time.UTC), 25),
+		// Monday 01:00 UTC is still Sunday 22:00 in São Paulo.
+		completed(time.Date(2024, 9, 2, 1, 0, 0, 0, time.UTC), 40),
+		{Status: trips.StatusCanceled, BeginTime: time.Date(2024, 9, 2, 11, 0, 0, 0, time.UTC), Fare: 5},
+	}
+
+	counts := BuildHeatmap(tripList, saoPaulo, false)
+	if counts.Cells[0][8] != 2 || counts.Cells[6][22] != 1 || counts.Max != 2 || counts.Total != 3 {
+		t.Errorf("unexpected counts: monday 8h %v, sunday 22h %v, max %v, total %v", counts.Cells[0][8], counts.Cells[6][22], counts.Max, counts.Total)
+	}
+	if counts.Weekday(0) != time.Monday || counts.Weekday(6) != time.Sunday {
+		t.Errorf("expected rows from Monday to Sunday")
+	}
+
+	spend := BuildHeatmap(tripList, saoPaulo, true)
+	if spend.Cells[0][8] != 45 || spend.Max != 45 || spend.Total != 85 {
+		t.Errorf("unexpected spend: monday 8h %v, max %v, total %v", spend.Cells[0][8], spend.Max, spend.Total)
+	}
+}
+
+func TestTripsAt(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{{ID: "loc-1"}, {ID: "loc-2"}},
+		NextID:    3,
+		Aliases:   map[string]string{"loc-9": "loc-1"},
+	}
+	tripList := []trips.Trip{
+		{UUID: "a", PickupLocationID: "loc-1", DropoffLocationID: "loc-2"},
+		{UUID: "b", PickupLocationID: "loc-2", DropoffLocationID: "loc-9"},
+		{UUID: "c", PickupLocationID: "loc-2", DropoffLocationID: "loc-2"},
+	}
+
+	got := TripsAt(registry, "loc-1", tripList)
+	if len(got) != 2 || got[0].UUID != "a" || got[1].UUID != "b" {
+		t.Errorf("expected trips a and b, got %+v", got)
+	}
+}
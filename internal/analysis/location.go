@@ -0,0 +1,92 @@
+// Package analysis computes aggregates over trips and saved locations.
+package analysis
+
+import (
+	"cmp"
+	"slices"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+// LocationTrip is a trip that started or ended at a location. OtherID is the
+// location at the other end of the trip.
+type LocationTrip struct {
+	Trip    trips.Trip
+	Role    locations.VisitRole
+	OtherID string
+}
+
+type Companion struct {
+	ID    string
+	Count int
+}
+
+// LocationActivity summarizes the completed trips that started or ended at a
+// location. Spend is keyed by currency.
+type LocationActivity struct {
+	Trips      []LocationTrip
+	Companions []Companion
+	SpendFrom  map[string]float64
+	SpendTo    map[string]float64
+}
+
+// ActivityAt collects the completed trips to and from the location with the
+// given ID, resolving merged location IDs through the registry. Trips are
+// ordered by time and companions by how often they appear.
+func ActivityAt(registry *locations.Registry, id string, tripList []trips.Trip) LocationActivity {
+	activity := LocationActivity{
+		SpendFrom: make(map[string]float64),
+		SpendTo:   make(map[string]float64),
+	}
+
+	counts := make(map[string]int)
+	for _, trip := range tripList {
+		if trip.Status != trips.StatusCompleted {
+			continue
+		}
+
+		pickup := resolveID(registry, trip.PickupLocationID)
+		dropoff := resolveID(registry, trip.DropoffLocationID)
+
+		if pickup == id {
+			activity.Trips = append(activity.Trips, LocationTrip{Trip: trip, Role: locations.VisitPickup, OtherID: dropoff})
+			activity.SpendFrom[trip.Currency] += trip.Fare
+			if dropoff != "" && dropoff != id {
+				counts[dropoff]++
+			}
+		}
+		if dropoff == id {
+			activity.Trips = append(activity.Trips, LocationTrip{Trip: trip, Role: locations.VisitDropoff, OtherID: pickup})
+			activity.SpendTo[trip.Currency] += trip.Fare
+			if pickup != "" && pickup != id {
+				counts[pickup]++
+			}
+		}
+	}
+
+	slices.SortStableFunc(activity.Trips, func(a, b LocationTrip) int {
+		return a.Trip.BeginTime.Compare(b.Trip.BeginTime)
+	})
+
+	for other, n := range counts {
+		activity.Companions = append(activity.Companions, Companion{ID: other, Count: n})
+	}
+	slices.SortFunc(activity.Companions, func(a, b Companion) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.ID, b.ID))
+	})
+
+	return activity
+}
+
+// resolveID maps a trip's location ID to the current ID, following aliases of
+// merged locations. Unknown IDs are returned unchanged.
+func resolveID(registry *locations.Registry, id string) string {
+	if id == "" {
+		return ""
+	}
+	if loc, err := registry.Find(id); err == nil {
+		return loc.ID
+	}
+	return id
+}
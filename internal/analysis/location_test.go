@@ -0,0 +1,55 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestActivityAt(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{
+			{ID: "loc-1", Label: "office"},
+			{ID: "loc-2"},
+			{ID: "loc-3"},
+		},
+		NextID:  4,
+		Aliases: map[string]string{"loc-9": "loc-2"},
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 8, 0, 0, 0, time.UTC) }
+	tripList := []trips.Trip{
+		{UUID: "t3", BeginTime: day(3), Status: trips.StatusCompleted, Fare: 15, Currency: "BRL", PickupLocationID: "loc-1", DropoffLocationID: "loc-3"},
+		{UUID: "t1", BeginTime: day(1), Status: trips.StatusCompleted, Fare: 20, Currency: "BRL", PickupLocationID: "loc-9", DropoffLocationID: "loc-1"},
+		{UUID: "t2", BeginTime: day(2), Status: trips.StatusCompleted, Fare: 18, Currency: "BRL", PickupLocationID: "loc-1", DropoffLocationID: "loc-2"},
+		{UUID: "t4", BeginTime: day(4), Status: trips.StatusCompleted, Fare: 5, Currency: "USD", PickupLocationID: "loc-2", DropoffLocationID: "loc-1"},
+		{UUID: "t5", BeginTime: day(5), Status: trips.StatusCanceled, Fare: 3, Currency: "BRL", PickupLocationID: "loc-1"},
+		{UUID: "t6", BeginTime: day(6), Status: trips.StatusCompleted, Fare: 9, Currency: "BRL", PickupLocationID: "loc-2", DropoffLocationID: "loc-3"},
+	}
+
+	activity := ActivityAt(registry, "loc-1", tripList)
+
+	var uuids []string
+	for _, lt := range activity.Trips {
+		uuids = append(uuids, lt.Trip.UUID+"/"+string(lt.Role)+"/"+lt.OtherID)
+	}
+	want := []string{"t1/dropoff/loc-2", "t2/pickup/loc-2", "t3/pickup/loc-3", "t4/dropoff/loc-2"}
+	if len(uuids) != len(want) {
+		t.Fatalf("expected trips %v, got %v", want, uuids)
+	}
+	for i := range want {
+		if uuids[i] != want[i] {
+			t.Errorf("trip %d: expected %s, got %s", i, want[i], uuids[i])
+		}
+	}
+
+	if len(activity.Companions) != 2 || activity.Companions[0] != (Companion{ID: "loc-2", Count: 3}) || activity.Companions[1] != (Companion{ID: "loc-3", Count: 1}) {
+		t.Errorf("unexpected companions: %+v", activity.Companions)
+	}
+
+	if activity.SpendFrom["BRL"] != 33 || activity.SpendTo["BRL"] != 20 || activity.SpendTo["USD"] != 5 {
+		t.Errorf("unexpected spend: from %v, to %v", activity.SpendFrom, activity.SpendTo)
+	}
+}
@@ -0,0 +1,28 @@
+package analysis
+
+import (
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+// TripsNear returns the trips whose pickup or dropoff lies within radius
+// meters of (lat, lon). Role limits the check to one end of the trip; an
+// empty role accepts either end. Ends without coordinates never match.
+func TripsNear(tripList []trips.Trip, lat, lon, radius float64, role locations.VisitRole) []trips.Trip {
+	within := func(pLat, pLon float64) bool {
+		if pLat == 0 && pLon == 0 {
+			return false
+		}
+		return locations.HaversineDistance(lat, lon, pLat, pLon) <= radius
+	}
+
+	var result []trips.Trip
+	for _, trip := range tripList {
+		pickup := role != locations.VisitDropoff && within(trip.PickupLat, trip.PickupLon)
+		dropoff := role != locations.VisitPickup && within(trip.DropoffLat, trip.DropoffLon)
+		if pickup || dropoff {
+			result = append(result, trip)
+		}
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"testing"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestTripsNear(t *testing.T) {
+	tripList := []trips.Trip{
+		{UUID: "to-office", PickupLat: 41.4120, PickupLon: -75.6580, DropoffLat: 41.4092, DropoffLon: -75.6621},
+		{UUID: "from-office", PickupLat: 41.4089, PickupLon: -75.6624, DropoffLat: 41.4120, DropoffLon: -75.6580},
+		{UUID: "elsewhere", PickupLat: 41.4120, PickupLon: -75.6580, DropoffLat: 41.4300, DropoffLon: -75.6400},
+		{UUID: "no-coordinates"},
+	}
+
+	tests := []struct {
+		role locations.VisitRole
+		want []string
+	}{
+		{"", []string{"to-office", "from-office"}},
+		{locations.VisitPickup, []string{"from-office"}},
+		{locations.VisitDropoff, []string{"to-office"}},
+	}
+
+	for _, tt := range tests {
+		got := TripsNear(tripList, 41.4089, -75.6624, 100, tt.role)
+		if len(got) != len(tt.want) {
+			t.Errorf("role %q: expected %v, got %d trips", tt.role, tt.want, len(got))
+			continue
+		}
+		for i := range got {
+			if got[i].UUID != tt.want[i] {
+				t.Errorf("role %q: expected %v, got %s at %d", tt.role, tt.want, got[i].UUID, i)
+			}
+		}
+	}
+}
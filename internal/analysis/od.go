@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"cmp"
+	"slices"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+// ODPair aggregates the completed trips from one location to another, in one
+// currency. Durations are in minutes.
+type ODPair struct {
+	PickupID       string  `json:"pickupLocationID"`
+	PickupLabel    string  `json:"pickupLabel,omitempty"`
+	DropoffID      string  `json:"dropoffLocationID"`
+	DropoffLabel   string  `json:"dropoffLabel,omitempty"`
+	Currency       string  `json:"currency"`
+	Trips          int     `json:"trips"`
+	TotalFare      float64 `json:"totalFare"`
+	MedianFare     float64 `json:"medianFare"`
+	MedianDuration float64 `json:"medianDurationMinutes"`
+}
+
+type odKey struct {
+	pickup   string
+	dropoff  string
+	currency string
+}
+
+// ODMatrix groups completed trips by pickup and dropoff location, resolving
+// merged location IDs through the registry. Trips missing either location
+// are left out. Pairs are ordered by trip count, busiest first.
+func ODMatrix(registry *locations.Registry, tripList []trips.Trip) []ODPair {
+	fares := make(map[odKey][]float64)
+	durations := make(map[odKey][]float64)
+	var keys []odKey
+
+	for _, trip := range tripList {
+		if trip.Status != trips.StatusCompleted {
+			continue
+		}
+
+		k := odKey{
+			pickup:   resolveID(registry, trip.PickupLocationID),
+			dropoff:  resolveID(registry, trip.DropoffLocationID),
+			currency: trip.Currency,
+		}
+		if k.pickup == "" || k.dropoff == "" {
+			continue
+		}
+
+		if _, ok := fares[k]; !ok {
+			keys = append(keys, k)
+		}
+		fares[k] = append(fares[k], trip.Fare)
+		if trip.Duration > 0 {
+			durations[k] = append(durations[k], trip.Duration)
+		}
+	}
+
+	pairs := make([]ODPair, 0, len(keys))
+	for _, k := range keys {
+		total := 0.0
+		for _, fare := range fares[k] {
+			total += fare
+		}
+		pairs = append(pairs, ODPair{
+			PickupID:       k.pickup,
+			PickupLabel:    registry.Label(k.pickup),
+			DropoffID:      k.dropoff,
+			DropoffLabel:   registry.Label(k.dropoff),
+			Currency:       k.currency,
+			Trips:          len(fares[k]),
+			TotalFare:      total,
+			MedianFare:     median(fares[k]),
+			MedianDuration: median(durations[k]),
+		})
+	}
+
+	slices.SortFunc(pairs, func(a, b ODPair) int {
+		return cmp.Or(
+			cmp.Compare(b.Trips, a.Trips),
+			cmp.Compare(b.TotalFare, a.TotalFare),
+			cmp.Compare(a.PickupID, b.PickupID),
+			cmp.Compare(a.DropoffID, b.DropoffID),
+			cmp.Compare(a.Currency, b.Currency),
+		)
+	})
+	return pairs
+}
@@ -0,0 +1,71 @@
+package analysis
+
+import (
+	"testing"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestODMatrix(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{
+			{ID: "loc-1", Label: "home"},
+			{ID: "loc-2", Label: "office"},
+			{ID: "loc-3"},
+		},
+		NextID:  4,
+		Aliases: map[string]string{"loc-9": "loc-2"},
+	}
+
+	completed := func(pickup, dropoff string, fare, duration float64) trips.Trip {
+		return trips.Trip{Status: trips.StatusCompleted, Currency: "BRL", Fare: fare, Duration: duration, PickupLocationID: pickup, DropoffLocationID: dropoff}
+	}
+	tripList := []trips.Trip{
+		completed("loc-1", "loc-2", 20, 25),
+		completed("loc-1", "loc-9", 30, 35),
+		completed("loc-1", "loc-2", 22, 0),
+		completed("loc-2", "loc-1", 25, 30),
+		completed("loc-2", "loc-3", 10, 12),
+		completed("loc-1", "", 15, 20),
+		{Status: trips.StatusCanceled, Currency: "BRL", Fare: 5, PickupLocationID: "loc-1", DropoffLocationID: "loc-2"},
+	}
+
+	pairs := ODMatrix(registry, tripList)
+	if len(pairs) != 3 {
+		t.Fatalf("expected 3 pairs, got %d: %+v", len(pairs), pairs)
+	}
+
+	commute := pairs[0]
+	if commute.PickupID != "loc-1" || commute.DropoffID != "loc-2" || commute.PickupLabel != "home" || commute.DropoffLabel != "office" {
+		t.Errorf("expected home -> office first, got %+v", commute)
+	}
+	if commute.Trips != 3 || commute.TotalFare != 72 || commute.MedianFare != 22 {
+		t.Errorf("unexpected fares: %+v", commute)
+	}
+	if commute.MedianDuration != 30 {
+		t.Errorf("expected median duration 30 ignoring unknown durations, got %v", commute.MedianDuration)
+	}
+
+	if pairs[1].PickupID != "loc-2" || pairs[1].DropoffID != "loc-1" {
+		t.Errorf("expected office -> home second by total fare, got %+v", pairs[1])
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{nil, 0},
+		{[]float64{3}, 3},
+		{[]float64{5, 1, 3}, 3},
+		{[]float64{4, 1, 3, 2}, 2.5},
+	}
+
+	for _, tt := range tests {
+		if got := median(tt.values); got != tt.want {
+			t.Errorf("median(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+	}
+}
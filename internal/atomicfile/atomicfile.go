@@ -0,0 +1,107 @@
+// Package atomicfile writes files atomically and guards read-modify-write
+// cycles with advisory locks, so crashes and concurrent ue processes cannot
+// leave state files half written.
+package atomicfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const lockPollInterval = 50 * time.Millisecond
+
+var ErrLocked = errors.New("locked by another process")
+
+// WriteFile writes data to a temporary file in the same directory, syncs it
+// and renames it over path, so readers see either the old or the new file.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmp := f.Name()
+
+	if err := writeAndSync(f, data, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return syncDir(dir)
+}
+
+func writeAndSync(f *os.File, data []byte, perm os.FileMode) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	return f.Close()
+}
+
+// syncDir makes the rename durable. Directories cannot be synced on every
+// platform, so failures to open or sync are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer d.Close()
+	d.Sync()
+	return nil
+}
+
+// Lock is an exclusive advisory lock held on a lock file.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes an exclusive lock on path, creating the file if needed, and
+// waits up to timeout for another process to release it.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(f)
+		if err == nil {
+			return &Lock{f: f}, nil
+		}
+		if !errors.Is(err, ErrLocked) || time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+func (l *Lock) Release() error {
+	if err := unlock(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
@@ -0,0 +1,81 @@
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteFileReplacesContent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	if err := WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+	if err := WriteFile(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "new" {
+		t.Errorf("expected new content, got %q", data)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestWriteFileMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+
+	if err := WriteFile(path, []byte("data"), 0644); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestAcquireIsExclusive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("advisory locks are not implemented on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "state.lock")
+
+	lock, err := Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() failed: %v", err)
+	}
+
+	if _, err := Acquire(path, 100*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked while held, got %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+
+	again, err := Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() after Release() failed: %v", err)
+	}
+	again.Release()
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package atomicfile
+
+import "os"
+
+// Advisory locks are only implemented on Unix; elsewhere locking is a no-op
+// and writes rely on atomic renames alone.
+func tryLock(f *os.File) error {
+	return nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"os/user"
 	"path/filepath"
 	"time"
+
+	"uber-extractor/internal/atomicfile"
 )
 
 const (
@@ -62,7 +64,7 @@ func Save(cookie, email string) error {
 		return err
 	}
 
-	if err := os.WriteFile(path, data, 0600); err != nil {
+	if err := atomicfile.WriteFile(path, data, 0600); err != nil {
 		return fmt.Errorf("failed to write credentials file: %w", err)
 	}
 
@@ -0,0 +1,257 @@
+// Package budget keeps spend budgets per calendar period and compares them
+// with the fares of completed trips.
+package budget
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"uber-extractor/internal/atomicfile"
+	"uber-extractor/internal/auth"
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrInvalidBudget  = errors.New("invalid budget")
+	ErrOverBudget     = errors.New("over budget")
+)
+
+type Period string
+
+const (
+	PeriodWeek    Period = "week"
+	PeriodMonth   Period = "month"
+	PeriodQuarter Period = "quarter"
+	PeriodYear    Period = "year"
+)
+
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(strings.ToLower(strings.TrimSpace(s))); p {
+	case PeriodWeek, PeriodMonth, PeriodQuarter, PeriodYear:
+		return p, nil
+	case "":
+		return PeriodMonth, nil
+	default:
+		return "", fmt.Errorf("%w: unknown period %q: expected week, month, quarter or year", ErrInvalidBudget, s)
+	}
+}
+
+// Range returns the calendar period containing now, in now's time zone.
+// Weeks start on Monday.
+func (p Period) Range(now time.Time) (time.Time, time.Time) {
+	y, m, d := now.Date()
+	loc := now.Location()
+
+	switch p {
+	case PeriodWeek:
+		start := time.Date(y, m, d-(int(now.Weekday())+6)%7, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7)
+	case PeriodQuarter:
+		start := time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 3, 0)
+	case PeriodYear:
+		start := time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0)
+	default:
+		start := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0)
+	}
+}
+
+// Budget limits the spend on completed trips paid in Currency in each
+// calendar period; fares in other currencies are never added to it. When set,
+// Location (a label or ID matching either end of a trip) and VehicleType
+// narrow down which trips count.
+type Budget struct {
+	Name        string  `json:"name"`
+	Period      Period  `json:"period"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency,omitempty"`
+	Location    string  `json:"location,omitempty"`
+	VehicleType string  `json:"vehicleType,omitempty"`
+}
+
+type Config struct {
+	Budgets []Budget `json:"budgets"`
+}
+
+// Set adds b, replacing a budget with the same name.
+func (c *Config) Set(b Budget) error {
+	b.Name = strings.TrimSpace(b.Name)
+	if b.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidBudget)
+	}
+	if b.Amount <= 0 {
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidBudget)
+	}
+	b.Currency = strings.ToUpper(strings.TrimSpace(b.Currency))
+	if b.Currency == "" {
+		return fmt.Errorf("%w: currency is required", ErrInvalidBudget)
+	}
+
+	i := slices.IndexFunc(c.Budgets, func(other Budget) bool { return strings.EqualFold(other.Name, b.Name) })
+	if i == -1 {
+		c.Budgets = append(c.Budgets, b)
+	} else {
+		c.Budgets[i] = b
+	}
+	return nil
+}
+
+func (c *Config) Remove(name string) (Budget, error) {
+	i := slices.IndexFunc(c.Budgets, func(b Budget) bool { return strings.EqualFold(b.Name, strings.TrimSpace(name)) })
+	if i == -1 {
+		return Budget{}, fmt.Errorf("%w: %s", ErrBudgetNotFound, name)
+	}
+	removed := c.Budgets[i]
+	c.Budgets = slices.Delete(c.Budgets, i, i+1)
+	return removed, nil
+}
+
+func getDefaultPath() string {
+	dir, err := auth.GetConfigDir()
+	if err != nil {
+		return "budgets.json"
+	}
+	return filepath.Join(dir, "budgets.json")
+}
+
+func Load(path ...string) (*Config, error) {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return &Config{Budgets: []Budget{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read budgets: %w", err)
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", p, err)
+	}
+	for i, b := range c.Budgets {
+		period, err := ParsePeriod(string(b.Period))
+		if err != nil {
+			return nil, fmt.Errorf("%s: budget %q: %w", p, b.Name, err)
+		}
+		c.Budgets[i].Period = period
+	}
+	return &c, nil
+}
+
+func Save(c *Config, path ...string) error {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	if err := atomicfile.WriteFile(p, data, 0644); err != nil {
+		return fmt.Errorf("failed to write budgets: %w", err)
+	}
+
+	slog.Info("Saved budgets", "path", p, "count", len(c.Budgets))
+	return nil
+}
+
+// Status compares a budget with the spend in the period containing now.
+// Projected extrapolates the spend so far to the end of the period.
+type Status struct {
+	Budget    Budget    `json:"budget"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Trips     int       `json:"trips"`
+	Spent     float64   `json:"spent"`
+	Remaining float64   `json:"remaining"`
+	Projected float64   `json:"projected"`
+	Over      bool      `json:"over"`
+}
+
+func (s Status) ProjectedOver() bool {
+	return s.Projected > s.Budget.Amount
+}
+
+// Evaluate sums the fares of the completed trips that count towards each
+// budget from the start of its current period until now. Trips without a
+// currency are taken to be in fallback, like the formatters do.
+func Evaluate(budgets []Budget, registry *locations.Registry, tripList []trips.Trip, fallback string, now time.Time) ([]Status, error) {
+	var statuses []Status
+	for _, b := range budgets {
+		if b.Currency == "" {
+			return nil, fmt.Errorf("%w: budget %q has no currency", ErrInvalidBudget, b.Name)
+		}
+
+		locationID := ""
+		if b.Location != "" {
+			loc, err := registry.Find(b.Location)
+			if err != nil {
+				return nil, fmt.Errorf("budget %q: %w", b.Name, err)
+			}
+			locationID = loc.ID
+		}
+
+		s := Status{Budget: b}
+		s.Start, s.End = b.Period.Range(now)
+
+		for _, trip := range tripList {
+			if trip.Status != trips.StatusCompleted || trip.BeginTime.Before(s.Start) || trip.BeginTime.After(now) {
+				continue
+			}
+			if !strings.EqualFold(cmp.Or(trip.Currency, fallback), b.Currency) {
+				continue
+			}
+			if b.VehicleType != "" && !strings.EqualFold(trip.VehicleType, b.VehicleType) {
+				continue
+			}
+			if locationID != "" && !atLocation(registry, trip, locationID) {
+				continue
+			}
+			s.Trips++
+			s.Spent += trip.Fare
+		}
+
+		s.Remaining = b.Amount - s.Spent
+		s.Over = s.Spent > b.Amount
+		s.Projected = s.Spent
+		if elapsed := now.Sub(s.Start); elapsed > 0 && now.Before(s.End) {
+			s.Projected = s.Spent * float64(s.End.Sub(s.Start)) / float64(elapsed)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+func atLocation(registry *locations.Registry, trip trips.Trip, id string) bool {
+	for _, ref := range []string{trip.PickupLocationID, trip.DropoffLocationID} {
+		if ref == "" {
+			continue
+		}
+		if loc, err := registry.Find(ref); err == nil && loc.ID == id {
+			return true
+		}
+	}
+	return false
+}
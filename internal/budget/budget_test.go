@@ -0,0 +1,163 @@
+package budget
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func TestPeriodRange(t *testing.T) {
+	now := time.Date(2024, 8, 15, 10, 0, 0, 0, time.UTC) // Thursday
+
+	tests := []struct {
+		period     Period
+		start, end string
+	}{
+		{PeriodWeek, "2024-08-12", "2024-08-19"},
+		{PeriodMonth, "2024-08-01", "2024-09-01"},
+		{PeriodQuarter, "2024-07-01", "2024-10-01"},
+		{PeriodYear, "2024-01-01", "2025-01-01"},
+	}
+
+	for _, tt := range tests {
+		start, end := tt.period.Range(now)
+		if start.Format("2006-01-02") != tt.start || end.Format("2006-01-02") != tt.end {
+			t.Errorf("%s: expected %s to %s, got %s to %s", tt.period, tt.start, tt.end, start.Format("2006-01-02"), end.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	if p, err := ParsePeriod(""); err != nil || p != PeriodMonth {
+		t.Errorf("expected empty period to default to month, got %q, %v", p, err)
+	}
+	if p, err := ParsePeriod("Quarter"); err != nil || p != PeriodQuarter {
+		t.Errorf("expected quarter, got %q, %v", p, err)
+	}
+	if _, err := ParsePeriod("fortnight"); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("expected ErrInvalidBudget, got %v", err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{{ID: "loc-1", Label: "office"}, {ID: "loc-2"}},
+		NextID:    3,
+		Aliases:   map[string]string{"loc-9": "loc-1"},
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 9, 0, 0, 0, time.UTC) }
+	trip := func(d int, fare float64, vehicle, pickup, dropoff string) trips.Trip {
+		return trips.Trip{Status: trips.StatusCompleted, BeginTime: day(d), Fare: fare, Currency: "BRL", VehicleType: vehicle, PickupLocationID: pickup, DropoffLocationID: dropoff}
+	}
+	tripList := []trips.Trip{
+		trip(2, 100, "UberX", "loc-2", "loc-1"),
+		trip(5, 150, "Comfort", "loc-9", "loc-2"),
+		trip(8, 50, "UberX", "loc-2", "loc-2"),
+		{Status: trips.StatusCanceled, BeginTime: day(9), Fare: 10, Currency: "BRL"},
+		trip(20, 500, "UberX", "loc-1", "loc-2"), // after now
+		{Status: trips.StatusCompleted, BeginTime: time.Date(2024, 5, 31, 9, 0, 0, 0, time.UTC), Fare: 80, Currency: "BRL"},
+		{Status: trips.StatusCompleted, BeginTime: day(3), Fare: 40, Currency: "USD", VehicleType: "UberX", PickupLocationID: "loc-1"},
+	}
+
+	budgets := []Budget{
+		{Name: "total", Period: PeriodMonth, Amount: 400, Currency: "BRL"},
+		{Name: "office", Period: PeriodMonth, Amount: 200, Location: "office", Currency: "BRL"},
+		{Name: "uberx", Period: PeriodMonth, Amount: 100, VehicleType: "uberx", Currency: "BRL"},
+	}
+
+	now := time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC) // a third of June
+	statuses, err := Evaluate(budgets, registry, tripList, "USD", now)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+
+	total, office, uberx := statuses[0], statuses[1], statuses[2]
+	if total.Spent != 300 || total.Trips != 3 || total.Over || total.Projected != 900 || !total.ProjectedOver() {
+		t.Errorf("unexpected total status: %+v", total)
+	}
+	if office.Spent != 250 || !office.Over {
+		t.Errorf("expected office budget over with 250 spent, got %+v", office)
+	}
+	if uberx.Spent != 150 || uberx.Remaining != -50 {
+		t.Errorf("unexpected uberx status: %+v", uberx)
+	}
+
+	if _, err := Evaluate([]Budget{{Name: "legacy", Amount: 1, Period: PeriodMonth}}, registry, tripList, "USD", now); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("expected ErrInvalidBudget for a budget without a currency, got %v", err)
+	}
+
+	if _, err := Evaluate([]Budget{{Name: "gym", Amount: 1, Period: PeriodMonth, Location: "gym", Currency: "BRL"}}, registry, tripList, "USD", now); !errors.Is(err, locations.ErrLocationNotFound) {
+		t.Errorf("expected ErrLocationNotFound for an unknown location, got %v", err)
+	}
+}
+
+func TestEvaluateFallbackCurrency(t *testing.T) {
+	registry := &locations.Registry{Locations: []locations.Location{}, NextID: 1}
+	now := time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC)
+	tripList := []trips.Trip{
+		{Status: trips.StatusCompleted, BeginTime: time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC), Fare: 70},
+		{Status: trips.StatusCompleted, BeginTime: time.Date(2024, 6, 4, 9, 0, 0, 0, time.UTC), Fare: 50, Currency: "BRL"},
+		{Status: trips.StatusCompleted, BeginTime: time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC), Fare: 40, Currency: "USD"},
+	}
+	budgets := []Budget{{Name: "transport", Period: PeriodMonth, Amount: 100, Currency: "BRL"}}
+
+	statuses, err := Evaluate(budgets, registry, tripList, "BRL", now)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if s := statuses[0]; s.Spent != 120 || s.Trips != 2 || !s.Over {
+		t.Errorf("expected the trip without a currency counted as BRL, got %+v", s)
+	}
+
+	statuses, err = Evaluate(budgets, registry, tripList, "USD", now)
+	if err != nil {
+		t.Fatalf("Evaluate() failed: %v", err)
+	}
+	if s := statuses[0]; s.Spent != 50 || s.Over {
+		t.Errorf("expected the trip without a currency counted as USD, got %+v", s)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budgets.json")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() of missing file failed: %v", err)
+	}
+	if err := c.Set(Budget{Name: "Transport", Period: PeriodMonth, Amount: 800, Currency: "BRL"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(Budget{Name: "transport", Period: PeriodWeek, Amount: 200, Currency: "brl"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(Budget{Name: "empty", Currency: "BRL"}); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("expected ErrInvalidBudget for a zero amount, got %v", err)
+	}
+	if err := c.Set(Budget{Name: "mixed", Amount: 100}); !errors.Is(err, ErrInvalidBudget) {
+		t.Errorf("expected ErrInvalidBudget without a currency, got %v", err)
+	}
+	if err := Save(c, path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(loaded.Budgets) != 1 || loaded.Budgets[0].Period != PeriodWeek || loaded.Budgets[0].Amount != 200 || loaded.Budgets[0].Currency != "BRL" {
+		t.Errorf("expected the budget to be replaced, got %+v", loaded.Budgets)
+	}
+
+	if _, err := loaded.Remove("TRANSPORT"); err != nil {
+		t.Errorf("Remove() failed: %v", err)
+	}
+	if _, err := loaded.Remove("transport"); !errors.Is(err, ErrBudgetNotFound) {
+		t.Errorf("expected ErrBudgetNotFound, got %v", err)
+	}
+}
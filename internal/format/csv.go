@@ -4,34 +4,126 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"uber-extractor/internal/trips"
 )
 
-type CSVFormatter struct{}
+const utf8BOM = "\ufeff"
+
+type csvColumn struct {
+	Name  string
+	Value func(t trips.Trip, nf numberFormat) string
+}
+
+var csvColumns = []csvColumn{
+	{"UUID", func(t trips.Trip, _ numberFormat) string { return t.UUID }},
+	{"BeginTime", func(t trips.Trip, _ numberFormat) string { return FormatTime(t.BeginTime) }},
+	{"EndTime", func(t trips.Trip, _ numberFormat) string { return FormatTime(t.EndTime) }},
+	{"Status", func(t trips.Trip, _ numberFormat) string { return t.Status.String() }},
+	{"Fare", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.Fare, 2) }},
+	{"Currency", func(t trips.Trip, _ numberFormat) string { return t.Currency }},
+	{"Driver", func(t trips.Trip, _ numberFormat) string { return t.Driver }},
+	{"VehicleType", func(t trips.Trip, _ numberFormat) string { return t.VehicleType }},
+	{"Distance", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.Distance, 2) }},
+	{"Duration", func(t trips.Trip, _ numberFormat) string { return FormatDuration(t.Duration) }},
+	{"DurationMinutes", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.Duration, 0) }},
+	{"PickupAddress", func(t trips.Trip, _ numberFormat) string { return t.PickupAddress }},
+	{"DropoffAddress", func(t trips.Trip, _ numberFormat) string { return t.DropoffAddress }},
+	{"PickupLat", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.PickupLat, 6) }},
+	{"PickupLon", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.PickupLon, 6) }},
+	{"DropoffLat", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.DropoffLat, 6) }},
+	{"DropoffLon", func(t trips.Trip, nf numberFormat) string { return nf.Float(t.DropoffLon, 6) }},
+	{"Rating", func(t trips.Trip, _ numberFormat) string { return strconv.Itoa(t.Rating) }},
+	{"MapURL", func(t trips.Trip, _ numberFormat) string { return t.MapURL }},
+	{"PickupLocationID", func(t trips.Trip, _ numberFormat) string { return t.PickupLocationID }},
+	{"DropoffLocationID", func(t trips.Trip, _ numberFormat) string { return t.DropoffLocationID }},
+	{"PickupLabel", func(t trips.Trip, _ numberFormat) string { return t.PickupLabel }},
+	{"DropoffLabel", func(t trips.Trip, _ numberFormat) string { return t.DropoffLabel }},
+	{"PickupNeighbourhood", func(t trips.Trip, _ numberFormat) string { return t.PickupNeighbourhood }},
+	{"PickupCity", func(t trips.Trip, _ numberFormat) string { return t.PickupCity }},
+	{"PickupCountry", func(t trips.Trip, _ numberFormat) string { return t.PickupCountry }},
+	{"DropoffNeighbourhood", func(t trips.Trip, _ numberFormat) string { return t.DropoffNeighbourhood }},
+	{"DropoffCity", func(t trips.Trip, _ numberFormat) string { return t.DropoffCity }},
+	{"DropoffCountry", func(t trips.Trip, _ numberFormat) string { return t.DropoffCountry }},
+	{"CommuteType", func(t trips.Trip, _ numberFormat) string { return t.CommuteType }},
+}
+
+var DefaultCSVColumns = []string{
+	"UUID",
+	"BeginTime",
+	"EndTime",
+	"Status",
+	"Fare",
+	"Driver",
+	"VehicleType",
+	"Distance",
+	"Duration",
+	"PickupAddress",
+	"DropoffAddress",
+	"PickupLat",
+	"PickupLon",
+	"DropoffLat",
+	"DropoffLon",
+	"Rating",
+	"PickupLocationID",
+	"PickupLabel",
+	"DropoffLocationID",
+	"DropoffLabel",
+}
+
+type CSVFormatter struct {
+	Columns   []string
+	Delimiter rune
+	Locale    string
+	BOM       bool
+}
+
+func NewCSVFormatter(opts Options) (*CSVFormatter, error) {
+	f := &CSVFormatter{
+		Columns:   opts.Columns,
+		Delimiter: opts.Delimiter,
+		Locale:    opts.Locale,
+		BOM:       opts.BOM,
+	}
+
+	if _, err := f.resolveColumns(); err != nil {
+		return nil, err
+	}
+	if _, err := newNumberFormat(f.Locale); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
 
 func (f *CSVFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	columns, err := f.resolveColumns()
+	if err != nil {
+		return err
+	}
+
+	nf, err := newNumberFormat(f.Locale)
+	if err != nil {
+		return err
+	}
+
+	if f.BOM {
+		if _, err := io.WriteString(w, utf8BOM); err != nil {
+			return err
+		}
+	}
+
 	writer := csv.NewWriter(w)
-	defer writer.Flush()
-
-	headers := []string{
-		"UUID",
-		"BeginTime",
-		"EndTime",
-		"Status",
-		"Fare",
-		"Driver",
-		"VehicleType",
-		"Distance",
-		"Duration",
-		"PickupAddress",
-		"DropoffAddress",
-		"PickupLat",
-		"PickupLon",
-		"DropoffLat",
-		"DropoffLon",
-		"Rating",
+	if f.Delimiter != 0 {
+		writer.Comma = f.Delimiter
+	}
+
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = col.Name
 	}
 
 	if err := writer.Write(headers); err != nil {
@@ -39,23 +131,9 @@ func (f *CSVFormatter) Format(w io.Writer, tripList []trips.Trip) error {
 	}
 
 	for _, trip := range tripList {
-		record := []string{
-			trip.UUID,
-			FormatTime(trip.BeginTime),
-			FormatTime(trip.EndTime),
-			trip.Status.String(),
-			fmt.Sprintf("%.2f", trip.Fare),
-			trip.Driver,
-			trip.VehicleType,
-			fmt.Sprintf("%.2f", trip.Distance),
-			FormatDuration(trip.Duration),
-			trip.PickupAddress,
-			trip.DropoffAddress,
-			fmt.Sprintf("%.6f", trip.PickupLat),
-			fmt.Sprintf("%.6f", trip.PickupLon),
-			fmt.Sprintf("%.6f", trip.DropoffLat),
-			fmt.Sprintf("%.6f", trip.DropoffLon),
-			fmt.Sprintf("%d", trip.Rating),
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = col.Value(trip, nf)
 		}
 
 		if err := writer.Write(record); err != nil {
@@ -63,7 +141,59 @@ func (f *CSVFormatter) Format(w io.Writer, tripList []trips.Trip) error {
 		}
 	}
 
-	return nil
+	writer.Flush()
+	return writer.Error()
+}
+
+func (f *CSVFormatter) resolveColumns() ([]csvColumn, error) {
+	names := f.Columns
+	if len(names) == 0 {
+		names = DefaultCSVColumns
+	}
+
+	columns := make([]csvColumn, 0, len(names))
+	for _, name := range names {
+		col, ok := lookupCSVColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column: %s", name)
+		}
+		columns = append(columns, col)
+	}
+
+	return columns, nil
+}
+
+func lookupCSVColumn(name string) (csvColumn, bool) {
+	name = strings.TrimSpace(name)
+	for _, col := range csvColumns {
+		if strings.EqualFold(col.Name, name) {
+			return col, true
+		}
+	}
+	return csvColumn{}, false
+}
+
+func CSVColumnNames() []string {
+	names := make([]string, len(csvColumns))
+	for i, col := range csvColumns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+
+	r := []rune(s)
+	if len(r) != 1 || r[0] == '"' || r[0] == '\r' || r[0] == '\n' {
+		return 0, fmt.Errorf("invalid delimiter: %q", s)
+	}
+	return r[0], nil
 }
 
 func FormatTime(t time.Time) string {
@@ -32,6 +32,10 @@ func TestCSVFormatter(t *testing.T) {
 			DropoffLat:     40.7200,
 			DropoffLon:     -74.0100,
 			Rating:         5,
+
+			PickupLocationID:  "loc-1",
+			PickupLabel:       "home",
+			DropoffLocationID: "loc-2",
 		},
 		{
 			UUID:           "trip-002",
@@ -66,7 +70,7 @@ func TestCSVFormatter(t *testing.T) {
 		t.Errorf("expected 3 lines (header + 2 trips), got %d", len(lines))
 	}
 
-	expectedHeader := "UUID,BeginTime,EndTime,Status,Fare,Driver,VehicleType,Distance,Duration,PickupAddress,DropoffAddress,PickupLat,PickupLon,DropoffLat,DropoffLon,Rating"
+	expectedHeader := "UUID,BeginTime,EndTime,Status,Fare,Driver,VehicleType,Distance,Duration,PickupAddress,DropoffAddress,PickupLat,PickupLon,DropoffLat,DropoffLon,Rating,PickupLocationID,PickupLabel,DropoffLocationID,DropoffLabel"
 	if lines[0] != expectedHeader {
 		t.Errorf("header mismatch\nexpected: %s\ngot: %s", expectedHeader, lines[0])
 	}
@@ -83,6 +87,10 @@ func TestCSVFormatter(t *testing.T) {
 		t.Errorf("expected first trip to contain driver name")
 	}
 
+	if !strings.HasSuffix(lines[1], ",loc-1,home,loc-2,") {
+		t.Errorf("expected first trip to end with its locations, got %s", lines[1])
+	}
+
 	if !strings.Contains(lines[2], "trip-002") {
 		t.Errorf("expected second trip to contain UUID trip-002")
 	}
@@ -108,7 +116,7 @@ func TestCSVFormatterEmpty(t *testing.T) {
 		t.Errorf("expected 1 line (header only), got %d", len(lines))
 	}
 
-	expectedHeader := "UUID,BeginTime,EndTime,Status,Fare,Driver,VehicleType,Distance,Duration,PickupAddress,DropoffAddress,PickupLat,PickupLon,DropoffLat,DropoffLon,Rating"
+	expectedHeader := "UUID,BeginTime,EndTime,Status,Fare,Driver,VehicleType,Distance,Duration,PickupAddress,DropoffAddress,PickupLat,PickupLon,DropoffLat,DropoffLon,Rating,PickupLocationID,PickupLabel,DropoffLocationID,DropoffLabel"
 	if lines[0] != expectedHeader {
 		t.Errorf("header mismatch\nexpected: %s\ngot: %s", expectedHeader, lines[0])
 	}
@@ -153,3 +161,122 @@ func TestFormatDuration(t *testing.T) {
 		}
 	})
 }
+
+func TestCSVFormatterColumns(t *testing.T) {
+	formatter, err := NewCSVFormatter(Options{
+		Columns: []string{"uuid", "beginTime", "fare", "pickupLocationID", "mapUrl"},
+	})
+	if err != nil {
+		t.Fatalf("NewCSVFormatter() failed: %v", err)
+	}
+
+	tripList := []trips.Trip{
+		{
+			UUID:             "trip-001",
+			BeginTime:        time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			Fare:             25.5,
+			PickupLocationID: "loc-3",
+			MapURL:           "https://maps.example.com/map.png",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, tripList); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	expectedHeader := "UUID,BeginTime,Fare,PickupLocationID,MapURL"
+	if lines[0] != expectedHeader {
+		t.Errorf("header mismatch\nexpected: %s\ngot: %s", expectedHeader, lines[0])
+	}
+
+	expectedRow := "trip-001,2024-01-15T10:30:00Z,25.50,loc-3,https://maps.example.com/map.png"
+	if lines[1] != expectedRow {
+		t.Errorf("row mismatch\nexpected: %s\ngot: %s", expectedRow, lines[1])
+	}
+}
+
+func TestCSVFormatterUnknownColumn(t *testing.T) {
+	_, err := NewCSVFormatter(Options{Columns: []string{"uuid", "tip"}})
+	if err == nil {
+		t.Error("expected error for unknown column, got nil")
+	}
+}
+
+func TestCSVFormatterLocale(t *testing.T) {
+	formatter, err := NewCSVFormatter(Options{
+		Columns:   []string{"UUID", "Fare", "Distance", "PickupLat"},
+		Delimiter: ';',
+		Locale:    "pt-BR",
+		BOM:       true,
+	})
+	if err != nil {
+		t.Fatalf("NewCSVFormatter() failed: %v", err)
+	}
+
+	tripList := []trips.Trip{
+		{
+			UUID:      "trip-001",
+			Fare:      17.65,
+			Distance:  8.63,
+			PickupLat: -23.561414,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, tripList); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+	if !strings.HasPrefix(output, "\ufeff") {
+		t.Error("expected output to start with a UTF-8 BOM")
+	}
+
+	lines := strings.Split(strings.TrimSpace(strings.TrimPrefix(output, "\ufeff")), "\n")
+
+	if lines[0] != "UUID;Fare;Distance;PickupLat" {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+
+	if lines[1] != "trip-001;17,65;8,63;-23,561414" {
+		t.Errorf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestCSVFormatterUnsupportedLocale(t *testing.T) {
+	_, err := NewCSVFormatter(Options{Locale: "xx-YY"})
+	if err == nil {
+		t.Error("expected error for unsupported locale, got nil")
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    rune
+		wantErr bool
+	}{
+		{input: "", want: 0},
+		{input: ";", want: ';'},
+		{input: "tab", want: '\t'},
+		{input: `\t`, want: '\t'},
+		{input: "|", want: '|'},
+		{input: ";;", wantErr: true},
+		{input: `"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDelimiter(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDelimiter(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDelimiter(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"uber-extractor/internal/locations"
 	"uber-extractor/internal/trips"
 )
 
@@ -11,12 +12,44 @@ type Formatter interface {
 	Format(w io.Writer, tripList []trips.Trip) error
 }
 
-func GetFormatter(format string) (Formatter, error) {
+type Options struct {
+	Columns   []string
+	Delimiter rune
+	Locale    string
+	BOM       bool
+	Registry  *locations.Registry
+
+	ExpenseAccount string
+	AssetAccount   string
+	Currency       string
+}
+
+func GetFormatter(format string, opts Options) (Formatter, error) {
 	switch format {
 	case "json":
 		return &JSONFormatter{}, nil
 	case "csv":
-		return &CSVFormatter{}, nil
+		return NewCSVFormatter(opts)
+	case "xlsx":
+		return &XLSXFormatter{Registry: opts.Registry, Currency: opts.Currency}, nil
+	case "ledger", "hledger":
+		return &LedgerFormatter{
+			ExpenseAccount: opts.ExpenseAccount,
+			AssetAccount:   opts.AssetAccount,
+			Currency:       opts.Currency,
+		}, nil
+	case "ofx":
+		return &OFXFormatter{Currency: opts.Currency}, nil
+	case "qif":
+		return &QIFFormatter{ExpenseAccount: opts.ExpenseAccount, Currency: opts.Currency}, nil
+	case "ics":
+		return &ICSFormatter{Currency: opts.Currency}, nil
+	case "beancount":
+		return &BeancountFormatter{
+			ExpenseAccount: opts.ExpenseAccount,
+			AssetAccount:   opts.AssetAccount,
+			Currency:       opts.Currency,
+		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported format: %s", format)
 	}
@@ -0,0 +1,123 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"uber-extractor/internal/trips"
+)
+
+const icsLineLimit = 75
+
+type ICSFormatter struct {
+	Currency string
+}
+
+func (f *ICSFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	stamp := icsTime(time.Now())
+
+	var b strings.Builder
+
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//uber-extractor//ue//EN")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+
+	for _, trip := range journalTrips(tripList) {
+		end := trip.EndTime
+		if end.IsZero() || end.Before(trip.BeginTime) {
+			end = trip.BeginTime.Add(time.Duration(trip.Duration * float64(time.Minute)))
+		}
+
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+trip.UUID+"@ue")
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		writeICSLine(&b, "DTSTART:"+icsTime(trip.BeginTime))
+		writeICSLine(&b, "DTEND:"+icsTime(end))
+		writeICSLine(&b, "SUMMARY:"+escapeICS(icsSummary(trip)))
+		if trip.PickupAddress != "" {
+			writeICSLine(&b, "LOCATION:"+escapeICS(trip.PickupAddress))
+		}
+		if trip.PickupLat != 0 || trip.PickupLon != 0 {
+			writeICSLine(&b, fmt.Sprintf("GEO:%.6f;%.6f", trip.PickupLat, trip.PickupLon))
+		}
+		writeICSLine(&b, "DESCRIPTION:"+escapeICS(icsDescription(trip, f.Currency)))
+		writeICSLine(&b, "END:VEVENT")
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func icsSummary(trip trips.Trip) string {
+	if trip.VehicleType != "" {
+		return "Uber " + trip.VehicleType
+	}
+	return "Uber trip"
+}
+
+func icsDescription(trip trips.Trip, currency string) string {
+	var lines []string
+	if trip.PickupLabel != "" {
+		lines = append(lines, "Pickup: "+trip.PickupLabel)
+	}
+	if dropoff := placeName(trip.DropoffLabel, trip.DropoffAddress); dropoff != "" {
+		lines = append(lines, "Dropoff: "+dropoff)
+	}
+	lines = append(lines, fmt.Sprintf("Fare: %s %s", formatAmount(trip.Fare), tripCurrency(trip, currency)))
+	if trip.Driver != "" {
+		lines = append(lines, "Driver: "+trip.Driver)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func placeName(label, address string) string {
+	switch {
+	case label != "" && address != "":
+		return fmt.Sprintf("%s (%s)", label, address)
+	case label != "":
+		return label
+	default:
+		return address
+	}
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escapeICS(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// writeICSLine folds content lines longer than 75 octets as required by
+// RFC 5545, without splitting multi-byte characters.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLimit - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
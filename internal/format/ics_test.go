@@ -0,0 +1,98 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"uber-extractor/internal/trips"
+)
+
+func TestICSFormatter(t *testing.T) {
+	tripList := []trips.Trip{
+		{
+			UUID:           "trip-001",
+			BeginTime:      time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			EndTime:        time.Date(2024, 1, 15, 10, 51, 0, 0, time.UTC),
+			Status:         trips.StatusCompleted,
+			Fare:           25.5,
+			Driver:         "John Doe",
+			VehicleType:    "UberX",
+			PickupAddress:  "1725 Slough Avenue, Scranton",
+			DropoffAddress: "123 Kellum Court, Scranton",
+			PickupLat:      41.4089,
+			PickupLon:      -75.6624,
+		},
+		{
+			UUID:      "trip-002",
+			BeginTime: time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC),
+			Status:    trips.StatusCompleted,
+			Duration:  15,
+		},
+		{
+			UUID:      "trip-003",
+			BeginTime: time.Date(2024, 1, 17, 8, 0, 0, 0, time.UTC),
+			Status:    trips.StatusCanceled,
+		},
+	}
+
+	formatter := &ICSFormatter{Currency: "BRL"}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, tripList); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") {
+		t.Error("expected VCALENDAR header with CRLF line endings")
+	}
+
+	if strings.Count(output, "BEGIN:VEVENT") != 2 {
+		t.Errorf("expected 2 events, got %d", strings.Count(output, "BEGIN:VEVENT"))
+	}
+
+	expected := []string{
+		"UID:trip-001@ue\r\n",
+		"DTSTART:20240115T103000Z\r\n",
+		"DTEND:20240115T105100Z\r\n",
+		"LOCATION:1725 Slough Avenue\\, Scranton\r\n",
+		"GEO:41.408900;-75.662400\r\n",
+		"DTEND:20240116T081500Z\r\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q", want)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(output, "\r\n ", "")
+	if !strings.Contains(unfolded, "DESCRIPTION:Dropoff: 123 Kellum Court\\, Scranton\\nFare: 25.50 BRL\\nDriver: John Doe\r\n") {
+		t.Error("expected description with dropoff, fare and driver")
+	}
+
+	if strings.Contains(output, "trip-003") {
+		t.Error("expected canceled trip to be skipped")
+	}
+}
+
+func TestWriteICSLine(t *testing.T) {
+	var b strings.Builder
+	writeICSLine(&b, "DESCRIPTION:"+strings.Repeat("ã", 40))
+
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("line exceeds %d octets: %d", icsLineLimit, len(line))
+		}
+		if !strings.HasPrefix(line, "DESCRIPTION:") && !strings.HasPrefix(line, " ") {
+			t.Errorf("expected continuation line to start with a space: %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(b.String(), "\r\n ", "")
+	if unfolded != "DESCRIPTION:"+strings.Repeat("ã", 40)+"\r\n" {
+		t.Errorf("unfolded line mismatch: %q", unfolded)
+	}
+}
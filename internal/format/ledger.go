@@ -0,0 +1,199 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"uber-extractor/internal/trips"
+)
+
+const (
+	DefaultExpenseAccount = "Expenses:Transport:Uber"
+	DefaultAssetAccount   = "Assets:Checking"
+	DefaultCurrency       = "BRL"
+)
+
+type LedgerFormatter struct {
+	ExpenseAccount string
+	AssetAccount   string
+	Currency       string
+}
+
+type BeancountFormatter struct {
+	ExpenseAccount string
+	AssetAccount   string
+	Currency       string
+}
+
+func (f *LedgerFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	expense, asset := accountsOrDefault(f.ExpenseAccount, f.AssetAccount)
+
+	var b strings.Builder
+	for _, trip := range journalTrips(tripList) {
+		amount := fmt.Sprintf("%s %s", formatAmount(trip.Fare), tripCurrency(trip, f.Currency))
+
+		fmt.Fprintf(&b, "%s * %s\n", trip.BeginTime.Format("2006-01-02"), tripPayee(trip))
+		fmt.Fprintf(&b, "    ; uuid: %s\n", trip.UUID)
+		if trip.PickupLocationID != "" {
+			fmt.Fprintf(&b, "    ; pickup: %s\n", trip.PickupLocationID)
+		}
+		if trip.DropoffLocationID != "" {
+			fmt.Fprintf(&b, "    ; dropoff: %s\n", trip.DropoffLocationID)
+		}
+		if trip.PickupLabel != "" {
+			fmt.Fprintf(&b, "    ; pickupLabel: %s\n", trip.PickupLabel)
+		}
+		if trip.DropoffLabel != "" {
+			fmt.Fprintf(&b, "    ; dropoffLabel: %s\n", trip.DropoffLabel)
+		}
+		fmt.Fprintf(&b, "    %-40s  %s\n", expense, amount)
+		fmt.Fprintf(&b, "    %s\n\n", asset)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (f *BeancountFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	expense, asset := accountsOrDefault(f.ExpenseAccount, f.AssetAccount)
+
+	journal := journalTrips(tripList)
+	if len(journal) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+
+	opened := journal[0].BeginTime.Format("2006-01-02")
+	fmt.Fprintf(&b, "%s open %s\n", opened, expense)
+	fmt.Fprintf(&b, "%s open %s\n\n", opened, asset)
+
+	for _, trip := range journal {
+		currency := tripCurrency(trip, f.Currency)
+		amount := formatAmount(trip.Fare)
+
+		fmt.Fprintf(&b, "%s * %s %s\n", trip.BeginTime.Format("2006-01-02"), beancountString(tripPayee(trip)), beancountString(tripNarration(trip)))
+		fmt.Fprintf(&b, "  uuid: %s\n", beancountString(trip.UUID))
+		if trip.PickupLocationID != "" {
+			fmt.Fprintf(&b, "  pickup: %s\n", beancountString(trip.PickupLocationID))
+		}
+		if trip.DropoffLocationID != "" {
+			fmt.Fprintf(&b, "  dropoff: %s\n", beancountString(trip.DropoffLocationID))
+		}
+		if trip.PickupLabel != "" {
+			fmt.Fprintf(&b, "  pickupLabel: %s\n", beancountString(trip.PickupLabel))
+		}
+		if trip.DropoffLabel != "" {
+			fmt.Fprintf(&b, "  dropoffLabel: %s\n", beancountString(trip.DropoffLabel))
+		}
+		fmt.Fprintf(&b, "  %-40s  %s %s\n", expense, amount, currency)
+		fmt.Fprintf(&b, "  %-40s  -%s %s\n\n", asset, amount, currency)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func journalTrips(tripList []trips.Trip) []trips.Trip {
+	var journal []trips.Trip
+	for _, trip := range tripList {
+		if trip.Status != trips.StatusCompleted || trip.BeginTime.IsZero() {
+			continue
+		}
+		journal = append(journal, trip)
+	}
+
+	slices.SortStableFunc(journal, func(a, b trips.Trip) int {
+		return a.BeginTime.Compare(b.BeginTime)
+	})
+	return journal
+}
+
+func accountsOrDefault(expense, asset string) (string, string) {
+	if expense == "" {
+		expense = DefaultExpenseAccount
+	}
+	if asset == "" {
+		asset = DefaultAssetAccount
+	}
+	return expense, asset
+}
+
+func tripCurrency(trip trips.Trip, fallback string) string {
+	if trip.Currency != "" {
+		return trip.Currency
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return DefaultCurrency
+}
+
+type currencyTrips struct {
+	currency string
+	trips    []trips.Trip
+}
+
+// groupByCurrency splits the journal by currency, in order of first
+// appearance, for formats that hold a single currency per statement or
+// account. An empty journal still yields one empty group in the fallback
+// currency.
+func groupByCurrency(journal []trips.Trip, fallback string) []currencyTrips {
+	groups := []currencyTrips{}
+	for _, trip := range journal {
+		currency := tripCurrency(trip, fallback)
+		i := slices.IndexFunc(groups, func(g currencyTrips) bool { return g.currency == currency })
+		if i == -1 {
+			groups = append(groups, currencyTrips{currency: currency})
+			i = len(groups) - 1
+		}
+		groups[i].trips = append(groups[i].trips, trip)
+	}
+	if len(groups) == 0 {
+		groups = append(groups, currencyTrips{currency: tripCurrency(trips.Trip{}, fallback)})
+	}
+	return groups
+}
+
+func tripPayee(trip trips.Trip) string {
+	switch {
+	case trip.Driver != "" && trip.VehicleType != "":
+		return fmt.Sprintf("%s (%s)", trip.Driver, trip.VehicleType)
+	case trip.Driver != "":
+		return trip.Driver
+	case trip.VehicleType != "":
+		return "Uber " + trip.VehicleType
+	default:
+		return "Uber"
+	}
+}
+
+func tripNarration(trip trips.Trip) string {
+	pickup := firstNonEmpty(trip.PickupLabel, trip.PickupAddress)
+	dropoff := firstNonEmpty(trip.DropoffLabel, trip.DropoffAddress)
+	if pickup == "" && dropoff == "" {
+		return "Uber trip"
+	}
+	return fmt.Sprintf("%s -> %s", pickup, dropoff)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func formatAmount(v float64) string {
+	return numberFormat{}.Float(v, 2)
+}
+
+func beancountString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,123 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"uber-extractor/internal/trips"
+)
+
+func journalTestTrips() []trips.Trip {
+	return []trips.Trip{
+		{
+			UUID:              "trip-002",
+			BeginTime:         time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC),
+			Status:            trips.StatusCompleted,
+			Fare:              12.3,
+			VehicleType:       "Comfort",
+			PickupAddress:     `The "Annex"`,
+			DropoffAddress:    "456 Oak Ave",
+			PickupLocationID:  "loc-2",
+			DropoffLocationID: "loc-1",
+			DropoffLabel:      "office",
+		},
+		{
+			UUID:              "trip-001",
+			BeginTime:         time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+			Status:            trips.StatusCompleted,
+			Fare:              25.5,
+			Currency:          "USD",
+			Driver:            "John Doe",
+			VehicleType:       "UberX",
+			PickupLocationID:  "loc-1",
+			DropoffLocationID: "loc-2",
+		},
+		{
+			UUID:      "trip-003",
+			BeginTime: time.Date(2024, 1, 17, 8, 0, 0, 0, time.UTC),
+			Status:    trips.StatusCanceled,
+		},
+	}
+}
+
+func TestLedgerFormatter(t *testing.T) {
+	formatter := &LedgerFormatter{AssetAccount: "Assets:Bank:Nubank", Currency: "BRL"}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, journalTestTrips()); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+
+	expected := "2024-01-15 * John Doe (UberX)\n" +
+		"    ; uuid: trip-001\n" +
+		"    ; pickup: loc-1\n" +
+		"    ; dropoff: loc-2\n" +
+		"    Expenses:Transport:Uber                   25.50 USD\n" +
+		"    Assets:Bank:Nubank\n\n"
+	if !strings.HasPrefix(output, expected) {
+		t.Errorf("unexpected first transaction\nexpected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if !strings.Contains(output, "2024-01-16 * Uber Comfort\n") {
+		t.Error("expected vehicle type payee for trip without driver")
+	}
+
+	if !strings.Contains(output, "    ; dropoffLabel: office\n") {
+		t.Error("expected dropoff label tag")
+	}
+
+	if !strings.Contains(output, "12.30 BRL") {
+		t.Error("expected fallback currency for trip without currency")
+	}
+
+	if strings.Contains(output, "trip-003") {
+		t.Error("expected canceled trip to be skipped")
+	}
+}
+
+func TestBeancountFormatter(t *testing.T) {
+	formatter := &BeancountFormatter{}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, journalTestTrips()); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "2024-01-15 open Expenses:Transport:Uber\n2024-01-15 open Assets:Checking\n\n") {
+		t.Errorf("expected open directives at the first trip date, got:\n%s", output)
+	}
+
+	expected := "2024-01-16 * \"Uber Comfort\" \"The \\\"Annex\\\" -> office\"\n" +
+		"  uuid: \"trip-002\"\n" +
+		"  pickup: \"loc-2\"\n" +
+		"  dropoff: \"loc-1\"\n" +
+		"  dropoffLabel: \"office\"\n" +
+		"  Expenses:Transport:Uber                   12.30 BRL\n" +
+		"  Assets:Checking                           -12.30 BRL\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("unexpected transaction\nexpected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if strings.Contains(output, "trip-003") {
+		t.Error("expected canceled trip to be skipped")
+	}
+}
+
+func TestBeancountFormatterEmpty(t *testing.T) {
+	formatter := &BeancountFormatter{}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, []trips.Trip{}); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("expected empty output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,46 @@
+package format
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var commaDecimalLanguages = map[string]bool{
+	"pt": true,
+	"es": true,
+	"fr": true,
+	"de": true,
+	"it": true,
+	"nl": true,
+}
+
+type numberFormat struct {
+	decimalComma bool
+}
+
+func newNumberFormat(locale string) (numberFormat, error) {
+	if locale == "" {
+		return numberFormat{}, nil
+	}
+
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	lang, _, _ = strings.Cut(lang, "_")
+
+	if commaDecimalLanguages[lang] {
+		return numberFormat{decimalComma: true}, nil
+	}
+	if lang == "en" {
+		return numberFormat{}, nil
+	}
+
+	return numberFormat{}, fmt.Errorf("unsupported locale: %s", locale)
+}
+
+func (nf numberFormat) Float(v float64, precision int) string {
+	s := strconv.FormatFloat(v, 'f', precision, 64)
+	if nf.decimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
@@ -0,0 +1,139 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"uber-extractor/internal/trips"
+)
+
+const (
+	ofxBankID    = "UBER"
+	ofxAccountID = "ue"
+	ofxNameLimit = 32
+)
+
+type OFXFormatter struct {
+	Currency string
+}
+
+func (f *OFXFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	fallback := f.Currency
+	if fallback == "" {
+		fallback = DefaultCurrency
+	}
+	statements := groupByCurrency(journalTrips(tripList), fallback)
+
+	now := time.Now()
+
+	var b strings.Builder
+
+	b.WriteString("OFXHEADER:100\n")
+	b.WriteString("DATA:OFXSGML\n")
+	b.WriteString("VERSION:102\n")
+	b.WriteString("SECURITY:NONE\n")
+	b.WriteString("ENCODING:USASCII\n")
+	b.WriteString("CHARSET:1252\n")
+	b.WriteString("COMPRESSION:NONE\n")
+	b.WriteString("OLDFILEUID:NONE\n")
+	b.WriteString("NEWFILEUID:NONE\n\n")
+
+	b.WriteString("<OFX>\n")
+	b.WriteString("<SIGNONMSGSRSV1>\n<SONRS>\n")
+	b.WriteString("<STATUS>\n<CODE>0</CODE>\n<SEVERITY>INFO</SEVERITY>\n</STATUS>\n")
+	fmt.Fprintf(&b, "<DTSERVER>%s</DTSERVER>\n", ofxTime(now))
+	b.WriteString("<LANGUAGE>ENG</LANGUAGE>\n")
+	b.WriteString("</SONRS>\n</SIGNONMSGSRSV1>\n")
+
+	b.WriteString("<BANKMSGSRSV1>\n")
+	for i, stmt := range statements {
+		start, end := now, now
+		if len(stmt.trips) > 0 {
+			start = stmt.trips[0].BeginTime
+			end = stmt.trips[len(stmt.trips)-1].BeginTime
+		}
+
+		b.WriteString("<STMTTRNRS>\n")
+		fmt.Fprintf(&b, "<TRNUID>%d</TRNUID>\n", i+1)
+		b.WriteString("<STATUS>\n<CODE>0</CODE>\n<SEVERITY>INFO</SEVERITY>\n</STATUS>\n")
+		b.WriteString("<STMTRS>\n")
+		fmt.Fprintf(&b, "<CURDEF>%s</CURDEF>\n", stmt.currency)
+		b.WriteString("<BANKACCTFROM>\n")
+		fmt.Fprintf(&b, "<BANKID>%s</BANKID>\n<ACCTID>%s-%s</ACCTID>\n<ACCTTYPE>CHECKING</ACCTTYPE>\n", ofxBankID, ofxAccountID, stmt.currency)
+		b.WriteString("</BANKACCTFROM>\n")
+
+		b.WriteString("<BANKTRANLIST>\n")
+		fmt.Fprintf(&b, "<DTSTART>%s</DTSTART>\n<DTEND>%s</DTEND>\n", ofxTime(start), ofxTime(end))
+		for _, trip := range stmt.trips {
+			b.WriteString("<STMTTRN>\n")
+			b.WriteString("<TRNTYPE>DEBIT</TRNTYPE>\n")
+			fmt.Fprintf(&b, "<DTPOSTED>%s</DTPOSTED>\n", ofxTime(trip.BeginTime))
+			fmt.Fprintf(&b, "<TRNAMT>-%s</TRNAMT>\n", formatAmount(trip.Fare))
+			fmt.Fprintf(&b, "<FITID>%s</FITID>\n", FITID(trip.UUID))
+			fmt.Fprintf(&b, "<NAME>%s</NAME>\n", escapeSGML(truncateRunes(tripPayee(trip), ofxNameLimit)))
+			fmt.Fprintf(&b, "<MEMO>%s</MEMO>\n", escapeSGML(tripNarration(trip)))
+			b.WriteString("</STMTTRN>\n")
+		}
+		b.WriteString("</BANKTRANLIST>\n")
+
+		b.WriteString("<LEDGERBAL>\n<BALAMT>0.00</BALAMT>\n")
+		fmt.Fprintf(&b, "<DTASOF>%s</DTASOF>\n", ofxTime(end))
+		b.WriteString("</LEDGERBAL>\n")
+
+		b.WriteString("</STMTRS>\n</STMTTRNRS>\n")
+	}
+	b.WriteString("</BANKMSGSRSV1>\n")
+	b.WriteString("</OFX>\n")
+
+	_, err := w.Write(windows1252(b.String()))
+	return err
+}
+
+// FITID derives the OFX transaction ID from the trip UUID so that importing
+// the same trip twice is recognized as a duplicate.
+func FITID(uuid string) string {
+	return strings.ToUpper(strings.ReplaceAll(uuid, "-", ""))
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func escapeSGML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func truncateRunes(s string, maxLen int) string {
+	r := []rune(s)
+	if len(r) <= maxLen {
+		return s
+	}
+	return string(r[:maxLen])
+}
+
+// windows1252 encodes s as declared by the CHARSET:1252 header. Characters
+// outside the code page are replaced with '?'.
+func windows1252(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			out = append(out, byte(r))
+		case cp1252Extras[r] != 0:
+			out = append(out, cp1252Extras[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// cp1252Extras maps the characters Windows-1252 places in 0x80-0x9f.
+var cp1252Extras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
@@ -0,0 +1,87 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOFXFormatter(t *testing.T) {
+	formatter := &OFXFormatter{Currency: "BRL"}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, journalTestTrips()); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "OFXHEADER:100\n") {
+		t.Error("expected OFX header")
+	}
+
+	if strings.Count(output, "<STMTTRN>") != 2 {
+		t.Errorf("expected 2 transactions, got %d", strings.Count(output, "<STMTTRN>"))
+	}
+
+	if !strings.Contains(output, "ENCODING:USASCII\nCHARSET:1252\n") {
+		t.Error("expected OFX 1.02 encoding and charset")
+	}
+
+	if strings.Count(output, "<STMTRS>") != 2 {
+		t.Errorf("expected one statement per currency, got %d", strings.Count(output, "<STMTRS>"))
+	}
+
+	_, usd, _ := strings.Cut(output, "<CURDEF>USD</CURDEF>")
+	usd, _, _ = strings.Cut(usd, "</STMTRS>")
+	if !strings.Contains(usd, "<ACCTID>ue-USD</ACCTID>") || !strings.Contains(usd, "<FITID>TRIP001</FITID>") || strings.Contains(usd, "<FITID>TRIP002</FITID>") {
+		t.Errorf("expected the USD trip alone in the USD statement, got:\n%s", usd)
+	}
+
+	if !strings.Contains(output, "<CURDEF>BRL</CURDEF>") {
+		t.Error("expected trips without a currency in a statement in the formatter currency")
+	}
+
+	if !strings.Contains(output, "<DTPOSTED>20240115103000[0:GMT]</DTPOSTED>\n<TRNAMT>-25.50</TRNAMT>\n<FITID>TRIP001</FITID>") {
+		t.Error("expected first trip transaction with date, amount and FITID")
+	}
+
+	if !strings.Contains(output, "<MEMO>The \"Annex\" -&gt; office</MEMO>") {
+		t.Error("expected escaped memo")
+	}
+
+	if strings.Contains(output, "TRIP003") {
+		t.Error("expected canceled trip to be skipped")
+	}
+}
+
+func TestOFXFormatterEncodesWindows1252(t *testing.T) {
+	tripList := journalTestTrips()[:1]
+	tripList[0].Driver = "João"
+
+	var buf bytes.Buffer
+	if err := (&OFXFormatter{}).Format(&buf, tripList); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte("<NAME>Jo\xe3o")) {
+		t.Error("expected name encoded in Windows-1252")
+	}
+}
+
+func TestFITID(t *testing.T) {
+	uuid := "6b8dc458-d2ea-42a1-97e9-7db671798503"
+
+	first := FITID(uuid)
+	if first != "6B8DC458D2EA42A197E97DB671798503" {
+		t.Errorf("unexpected FITID: %s", first)
+	}
+
+	if FITID(uuid) != first {
+		t.Error("expected FITID to be stable for the same UUID")
+	}
+
+	if FITID("7b8dc458-d2ea-42a1-97e9-7db671798503") == first {
+		t.Error("expected different UUIDs to produce different FITIDs")
+	}
+}
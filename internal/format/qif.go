@@ -0,0 +1,62 @@
+package format
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"uber-extractor/internal/trips"
+)
+
+type QIFFormatter struct {
+	ExpenseAccount string
+	// Currency is assumed for trips without one.
+	Currency string
+}
+
+// Format writes a single bank list when all trips share a currency. QIF has
+// no currency field, so trips in several currencies are written to one
+// account per currency ("Uber BRL", "Uber USD") instead of being mixed.
+func (f *QIFFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	expense, _ := accountsOrDefault(f.ExpenseAccount, "")
+	category := strings.TrimPrefix(expense, "Expenses:")
+
+	groups := groupByCurrency(journalTrips(tripList), f.Currency)
+
+	var b strings.Builder
+
+	if len(groups) > 1 {
+		b.WriteString("!Option:AutoSwitch\n")
+		for _, g := range groups {
+			fmt.Fprintf(&b, "!Account\nN%s\nTBank\n^\n", qifAccount(g.currency))
+		}
+		b.WriteString("!Clear:AutoSwitch\n")
+	}
+
+	for _, g := range groups {
+		if len(groups) > 1 {
+			fmt.Fprintf(&b, "!Account\nN%s\nTBank\n^\n", qifAccount(g.currency))
+		}
+		b.WriteString("!Type:Bank\n")
+		for _, trip := range g.trips {
+			fmt.Fprintf(&b, "D%s\n", trip.BeginTime.Format("01/02/2006"))
+			fmt.Fprintf(&b, "T-%s\n", formatAmount(trip.Fare))
+			fmt.Fprintf(&b, "N%s\n", FITID(trip.UUID))
+			fmt.Fprintf(&b, "P%s\n", qifField(tripPayee(trip)))
+			fmt.Fprintf(&b, "M%s\n", qifField(tripNarration(trip)))
+			fmt.Fprintf(&b, "L%s\n", category)
+			b.WriteString("^\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func qifAccount(currency string) string {
+	return "Uber " + currency
+}
+
+func qifField(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,61 @@
+package format
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQIFFormatter(t *testing.T) {
+	formatter := &QIFFormatter{Currency: "USD"}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, journalTestTrips()); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "!Type:Bank\n") {
+		t.Error("expected QIF bank header")
+	}
+
+	expected := "D01/15/2024\n" +
+		"T-25.50\n" +
+		"NTRIP001\n" +
+		"PJohn Doe (UberX)\n" +
+		"MUber trip\n" +
+		"LTransport:Uber\n" +
+		"^\n"
+	if !strings.Contains(output, expected) {
+		t.Errorf("unexpected transaction\nexpected:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if strings.Count(output, "^\n") != 2 {
+		t.Errorf("expected 2 transactions, got %d", strings.Count(output, "^\n"))
+	}
+}
+
+func TestQIFFormatterSplitsCurrencies(t *testing.T) {
+	formatter := &QIFFormatter{Currency: "BRL"}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, journalTestTrips()); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	output := buf.String()
+
+	if !strings.HasPrefix(output, "!Option:AutoSwitch\n!Account\nNUber USD\nTBank\n^\n!Account\nNUber BRL\nTBank\n^\n!Clear:AutoSwitch\n") {
+		t.Errorf("expected an account list with one account per currency, got:\n%s", output)
+	}
+
+	_, usd, _ := strings.Cut(output, "!Clear:AutoSwitch\n!Account\nNUber USD\nTBank\n^\n!Type:Bank\n")
+	usd, brl, _ := strings.Cut(usd, "!Account\nNUber BRL\nTBank\n^\n!Type:Bank\n")
+	if !strings.Contains(usd, "NTRIP001\n") || strings.Contains(usd, "NTRIP002\n") {
+		t.Errorf("expected only the USD trip in the USD account, got:\n%s", usd)
+	}
+	if !strings.Contains(brl, "NTRIP002\n") || strings.Contains(brl, "NTRIP001\n") {
+		t.Errorf("expected only the BRL trip in the BRL account, got:\n%s", brl)
+	}
+}
@@ -0,0 +1,377 @@
+package format
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleDateTime
+	xlsxStyleAmount
+	xlsxStyleDecimal
+	xlsxStyleCoordinate
+)
+
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+type XLSXFormatter struct {
+	Registry *locations.Registry
+	// Currency is assumed for trips without one.
+	Currency string
+}
+
+type xlsxPart struct {
+	name    string
+	content string
+}
+
+type xlsxCell struct {
+	value  string
+	number bool
+	style  int
+}
+
+type xlsxSheet struct {
+	name   string
+	widths []float64
+	rows   [][]xlsxCell
+}
+
+func (f *XLSXFormatter) Format(w io.Writer, tripList []trips.Trip) error {
+	sheets := []xlsxSheet{
+		tripsSheet(tripList, f.Currency),
+		summarySheet(tripList, f.Currency),
+		locationsSheet(f.Registry),
+	}
+
+	zw := zip.NewWriter(w)
+
+	parts := []xlsxPart{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(sheets)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, sheet := range sheets {
+		parts = append(parts, xlsxPart{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func tripsSheet(tripList []trips.Trip, currency string) xlsxSheet {
+	sheet := xlsxSheet{
+		name:   "Trips",
+		widths: []float64{38, 20, 20, 12, 10, 8, 20, 14, 10, 10, 40, 40, 12, 12, 12, 12, 8, 10, 10},
+	}
+
+	sheet.header("UUID", "Begin Time", "End Time", "Status", "Fare", "Currency", "Driver", "Vehicle Type",
+		"Distance", "Duration (min)", "Pickup Address", "Dropoff Address",
+		"Pickup Lat", "Pickup Lon", "Dropoff Lat", "Dropoff Lon", "Rating",
+		"Pickup Location", "Dropoff Location")
+
+	for _, trip := range tripList {
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			textCell(trip.UUID),
+			dateCell(trip.BeginTime),
+			dateCell(trip.EndTime),
+			textCell(trip.Status.String()),
+			numberCell(trip.Fare, xlsxStyleAmount),
+			textCell(tripCurrency(trip, currency)),
+			textCell(trip.Driver),
+			textCell(trip.VehicleType),
+			numberCell(trip.Distance, xlsxStyleDecimal),
+			numberCell(trip.Duration, xlsxStyleDefault),
+			textCell(trip.PickupAddress),
+			textCell(trip.DropoffAddress),
+			numberCell(trip.PickupLat, xlsxStyleCoordinate),
+			numberCell(trip.PickupLon, xlsxStyleCoordinate),
+			numberCell(trip.DropoffLat, xlsxStyleCoordinate),
+			numberCell(trip.DropoffLon, xlsxStyleCoordinate),
+			numberCell(float64(trip.Rating), xlsxStyleDefault),
+			textCell(firstNonEmpty(trip.PickupLabel, trip.PickupLocationID)),
+			textCell(firstNonEmpty(trip.DropoffLabel, trip.DropoffLocationID)),
+		})
+	}
+
+	return sheet
+}
+
+type monthSummary struct {
+	month     string
+	currency  string
+	count     int
+	completed int
+	canceled  int
+	fare      float64
+	distance  float64
+	duration  float64
+}
+
+// summarySheet has a row per month and currency. Fares are totalled and
+// averaged over completed trips only, and never across currencies.
+func summarySheet(tripList []trips.Trip, currency string) xlsxSheet {
+	sheet := xlsxSheet{
+		name:   "Summary",
+		widths: []float64{10, 10, 8, 12, 10, 12, 12, 14, 14},
+	}
+
+	sheet.header("Month", "Currency", "Trips", "Completed", "Canceled", "Total Fare", "Average Fare", "Distance", "Duration (min)")
+
+	type summaryKey struct{ month, currency string }
+	byMonth := make(map[summaryKey]*monthSummary)
+	for _, trip := range tripList {
+		if trip.BeginTime.IsZero() {
+			continue
+		}
+
+		k := summaryKey{trip.BeginTime.Format("2006-01"), tripCurrency(trip, currency)}
+		s, ok := byMonth[k]
+		if !ok {
+			s = &monthSummary{month: k.month, currency: k.currency}
+			byMonth[k] = s
+		}
+
+		s.count++
+		switch trip.Status {
+		case trips.StatusCompleted:
+			s.completed++
+			s.fare += trip.Fare
+		case trips.StatusCanceled:
+			s.canceled++
+		}
+		s.distance += trip.Distance
+		s.duration += trip.Duration
+	}
+
+	summaries := make([]*monthSummary, 0, len(byMonth))
+	for _, s := range byMonth {
+		summaries = append(summaries, s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].month != summaries[j].month {
+			return summaries[i].month < summaries[j].month
+		}
+		return summaries[i].currency < summaries[j].currency
+	})
+
+	for _, s := range summaries {
+		avgFare := 0.0
+		if s.completed > 0 {
+			avgFare = s.fare / float64(s.completed)
+		}
+
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			textCell(s.month),
+			textCell(s.currency),
+			numberCell(float64(s.count), xlsxStyleDefault),
+			numberCell(float64(s.completed), xlsxStyleDefault),
+			numberCell(float64(s.canceled), xlsxStyleDefault),
+			numberCell(s.fare, xlsxStyleAmount),
+			numberCell(avgFare, xlsxStyleAmount),
+			numberCell(s.distance, xlsxStyleDecimal),
+			numberCell(s.duration, xlsxStyleDefault),
+		})
+	}
+
+	return sheet
+}
+
+func locationsSheet(registry *locations.Registry) xlsxSheet {
+	sheet := xlsxSheet{
+		name:   "Locations",
+		widths: []float64{10, 16, 60, 12, 12, 8, 20, 20},
+	}
+
+	sheet.header("ID", "Label", "Address", "Latitude", "Longitude", "Visits", "First Seen", "Last Seen")
+
+	if registry == nil {
+		return sheet
+	}
+
+	for _, loc := range registry.Locations {
+		sheet.rows = append(sheet.rows, []xlsxCell{
+			textCell(loc.ID),
+			textCell(loc.Label),
+			textCell(loc.CanonicalAddress),
+			numberCell(loc.AvgLat, xlsxStyleCoordinate),
+			numberCell(loc.AvgLon, xlsxStyleCoordinate),
+			numberCell(float64(loc.VisitCount), xlsxStyleDefault),
+			dateCell(loc.FirstSeen),
+			dateCell(loc.LastSeen),
+		})
+	}
+
+	return sheet
+}
+
+func (s *xlsxSheet) header(names ...string) {
+	row := make([]xlsxCell, len(names))
+	for i, name := range names {
+		row[i] = xlsxCell{value: name, style: xlsxStyleHeader}
+	}
+	s.rows = append(s.rows, row)
+}
+
+func (s *xlsxSheet) xml() string {
+	var b strings.Builder
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+
+	if len(s.widths) > 0 {
+		b.WriteString("<cols>")
+		for i, width := range s.widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+		}
+		b.WriteString("</cols>")
+	}
+
+	b.WriteString("<sheetData>")
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := cellRef(c, r)
+			switch {
+			case cell.value == "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, cell.style)
+			case cell.number:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, cell.value)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.style, escapeXML(cell.value))
+			}
+		}
+		b.WriteString("</row>")
+	}
+	b.WriteString("</sheetData>")
+	b.WriteString("</worksheet>")
+
+	return b.String()
+}
+
+func textCell(s string) xlsxCell {
+	return xlsxCell{value: s}
+}
+
+func numberCell(v float64, style int) xlsxCell {
+	return xlsxCell{value: strconv.FormatFloat(v, 'f', -1, 64), number: true, style: style}
+}
+
+func dateCell(t time.Time) xlsxCell {
+	if t.IsZero() {
+		return xlsxCell{style: xlsxStyleDateTime}
+	}
+	return xlsxCell{value: strconv.FormatFloat(ExcelSerial(t), 'f', -1, 64), number: true, style: xlsxStyleDateTime}
+}
+
+// ExcelSerial converts t to an Excel serial date using its wall-clock time,
+// since spreadsheet cells carry no time zone.
+func ExcelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+func cellRef(col, row int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func xlsxContentTypes(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func xlsxWorkbook(sheets []xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	b.WriteString(`<sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escapeXML(sheet.name), i+1, i+1)
+	}
+	b.WriteString(`</sheets>`)
+	b.WriteString(`</workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(sheetCount int) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheetCount+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+const xlsxRootRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// Cell formats are indexed by the xlsxStyle constants.
+const xlsxStyles = xml.Header +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2">` +
+	`<numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/>` +
+	`<numFmt numFmtId="165" formatCode="0.000000"/>` +
+	`</numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="6">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
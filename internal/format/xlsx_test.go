@@ -0,0 +1,189 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func readZipFile(t *testing.T, zr *zip.Reader, name string) string {
+	t.Helper()
+
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatalf("missing %s in workbook: %v", name, err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+
+	if err := xml.Unmarshal(data, new(struct{})); err != nil {
+		t.Errorf("%s is not well-formed XML: %v", name, err)
+	}
+
+	return string(data)
+}
+
+func TestXLSXFormatter(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{
+			{
+				ID:               "loc-1",
+				CanonicalAddress: "1725 slough avenue & co",
+				AvgLat:           41.4089,
+				AvgLon:           -75.6624,
+				VisitCount:       3,
+				FirstSeen:        time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+				LastSeen:         time.Date(2024, 2, 1, 8, 0, 0, 0, time.UTC),
+			},
+		},
+		NextID: 2,
+	}
+
+	tripList := []trips.Trip{
+		{
+			UUID:             "trip-001",
+			BeginTime:        time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			EndTime:          time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC),
+			Status:           trips.StatusCompleted,
+			Fare:             25.50,
+			Driver:           "John Doe",
+			PickupLocationID: "loc-1",
+		},
+		{
+			UUID:      "trip-002",
+			BeginTime: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC),
+			Status:    trips.StatusCompleted,
+			Fare:      14.50,
+		},
+		{
+			UUID:      "trip-003",
+			BeginTime: time.Date(2024, 2, 3, 9, 0, 0, 0, time.UTC),
+			Status:    trips.StatusCanceled,
+			Fare:      5,
+		},
+		{
+			UUID:      "trip-004",
+			BeginTime: time.Date(2024, 1, 22, 9, 0, 0, 0, time.UTC),
+			Status:    trips.StatusCompleted,
+			Fare:      10,
+			Currency:  "USD",
+		},
+	}
+
+	formatter := &XLSXFormatter{Registry: registry, Currency: "BRL"}
+
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, tripList); err != nil {
+		t.Fatalf("Format() failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip archive: %v", err)
+	}
+
+	readZipFile(t, zr, "[Content_Types].xml")
+	readZipFile(t, zr, "xl/styles.xml")
+
+	workbook := readZipFile(t, zr, "xl/workbook.xml")
+	for _, name := range []string{"Trips", "Summary", "Locations"} {
+		if !strings.Contains(workbook, `name="`+name+`"`) {
+			t.Errorf("expected workbook to contain sheet %s", name)
+		}
+	}
+
+	tripSheet := readZipFile(t, zr, "xl/worksheets/sheet1.xml")
+	if !strings.Contains(tripSheet, "trip-001") {
+		t.Error("expected trips sheet to contain trip-001")
+	}
+	if !strings.Contains(tripSheet, `<c r="B2" s="2"><v>45306.5</v></c>`) {
+		t.Error("expected begin time to be stored as a typed date cell")
+	}
+	if !strings.Contains(tripSheet, `<c r="E2" s="3"><v>25.5</v></c>`) {
+		t.Error("expected fare to be stored as an amount cell")
+	}
+	if !strings.Contains(tripSheet, `<c r="F2" s="0" t="inlineStr"><is><t xml:space="preserve">BRL</t></is></c>`) {
+		t.Error("expected the fallback currency next to the fare")
+	}
+
+	summary := readZipFile(t, zr, "xl/worksheets/sheet2.xml")
+	if !strings.Contains(summary, "2024-01") || !strings.Contains(summary, "2024-02") {
+		t.Error("expected summary sheet to contain one row per month")
+	}
+	if !strings.Contains(summary, `<c r="F2" s="3"><v>40</v></c><c r="G2" s="3"><v>20</v></c>`) {
+		t.Error("expected January BRL total fare of 40 and average of 20")
+	}
+	if !strings.Contains(summary, `<c r="F3" s="3"><v>10</v></c>`) {
+		t.Error("expected January USD fares in their own row")
+	}
+	if !strings.Contains(summary, `<c r="F4" s="3"><v>0</v></c>`) {
+		t.Error("expected canceled fares to be left out of the February total")
+	}
+
+	locationSheet := readZipFile(t, zr, "xl/worksheets/sheet3.xml")
+	if !strings.Contains(locationSheet, "1725 slough avenue &amp; co") {
+		t.Error("expected locations sheet to contain escaped address")
+	}
+}
+
+func TestExcelSerial(t *testing.T) {
+	tests := []struct {
+		name  string
+		input time.Time
+		want  float64
+	}{
+		{
+			name:  "epoch",
+			input: time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC),
+			want:  0,
+		},
+		{
+			name:  "noon",
+			input: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+			want:  45306.5,
+		},
+		{
+			name:  "wall clock time is kept",
+			input: time.Date(2024, 1, 15, 12, 0, 0, 0, time.FixedZone("BRT", -3*60*60)),
+			want:  45306.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExcelSerial(tt.input)
+			if got != tt.want {
+				t.Errorf("ExcelSerial() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	tests := []struct {
+		col, row int
+		want     string
+	}{
+		{0, 0, "A1"},
+		{25, 9, "Z10"},
+		{26, 0, "AA1"},
+		{27, 1, "AB2"},
+	}
+
+	for _, tt := range tests {
+		if got := cellRef(tt.col, tt.row); got != tt.want {
+			t.Errorf("cellRef(%d, %d) = %s, want %s", tt.col, tt.row, got, tt.want)
+		}
+	}
+}
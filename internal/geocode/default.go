@@ -0,0 +1,34 @@
+package geocode
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"uber-extractor/internal/auth"
+)
+
+var defaultNames = []string{"gazetteer.csv", "gazetteer.txt"}
+
+// LoadDefault loads ~/.ue/gazetteer.csv or ~/.ue/gazetteer.txt. It returns
+// nil without an error when neither exists, since geocoding is optional.
+func LoadDefault() (*Gazetteer, error) {
+	dir, err := auth.GetConfigDir()
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, name := range defaultNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		g, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Gazetteer loaded", "path", path, "entries", g.Len())
+		return g, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,288 @@
+// Package geocode reverse-geocodes coordinates offline against a local
+// gazetteer of cities and neighbourhoods.
+package geocode
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"uber-extractor/internal/locations"
+)
+
+const (
+	// MaxCityDistance and MaxNeighbourhoodDistance are how far in meters a
+	// point may be from a gazetteer entry to be considered inside it.
+	MaxCityDistance          = 30000.0
+	MaxNeighbourhoodDistance = 3000.0
+
+	cellDegrees     = 0.1
+	metersPerDegree = 111320.0
+	geonamesColumns = 19
+)
+
+var ErrInvalidGazetteer = errors.New("invalid gazetteer")
+
+type Kind int
+
+const (
+	KindCity Kind = iota
+	KindNeighbourhood
+)
+
+type Entry struct {
+	Name    string
+	Kind    Kind
+	City    string
+	Country string
+	Lat     float64
+	Lon     float64
+}
+
+// Place is the result of a reverse lookup. Fields are empty when nothing in
+// the gazetteer is close enough.
+type Place struct {
+	Neighbourhood string
+	City          string
+	Country       string
+}
+
+func (p Place) IsZero() bool {
+	return p == Place{}
+}
+
+// String joins the non-empty parts, e.g. "Bela Vista, São Paulo, BR".
+func (p Place) String() string {
+	var parts []string
+	for _, s := range []string{p.Neighbourhood, p.City, p.Country} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+type cellKey struct {
+	lat int
+	lon int
+}
+
+type Gazetteer struct {
+	entries []Entry
+	cells   map[cellKey][]int
+}
+
+func NewGazetteer(entries []Entry) *Gazetteer {
+	g := &Gazetteer{entries: entries, cells: make(map[cellKey][]int)}
+	for i, e := range entries {
+		k := key(e.Lat, e.Lon)
+		g.cells[k] = append(g.cells[k], i)
+	}
+	return g
+}
+
+func (g *Gazetteer) Len() int {
+	return len(g.entries)
+}
+
+// Reverse returns the nearest neighbourhood and city around (lat, lon).
+// The country comes from the city, or from the neighbourhood when no city is
+// close enough.
+func (g *Gazetteer) Reverse(lat, lon float64) Place {
+	if lat == 0 && lon == 0 {
+		return Place{}
+	}
+
+	var place Place
+	if city, ok := g.nearest(lat, lon, KindCity, MaxCityDistance); ok {
+		place.City = city.Name
+		place.Country = city.Country
+	}
+	if hood, ok := g.nearest(lat, lon, KindNeighbourhood, MaxNeighbourhoodDistance); ok {
+		place.Neighbourhood = hood.Name
+		if place.City == "" {
+			place.City = hood.City
+		}
+		if place.Country == "" {
+			place.Country = hood.Country
+		}
+	}
+	return place
+}
+
+func (g *Gazetteer) nearest(lat, lon float64, kind Kind, radius float64) (Entry, bool) {
+	latCells := int(math.Ceil(radius / (metersPerDegree * cellDegrees)))
+	lonCells := latCells
+	if cos := math.Cos(lat * math.Pi / 180); cos > 0.01 {
+		lonCells = int(math.Ceil(radius / (metersPerDegree * cos * cellDegrees)))
+	}
+
+	center := key(lat, lon)
+	match := -1
+	best := radius
+	for dlat := -latCells; dlat <= latCells; dlat++ {
+		for dlon := -lonCells; dlon <= lonCells; dlon++ {
+			for _, i := range g.cells[cellKey{lat: center.lat + dlat, lon: center.lon + dlon}] {
+				e := g.entries[i]
+				if e.Kind != kind {
+					continue
+				}
+				distance := locations.HaversineDistance(lat, lon, e.Lat, e.Lon)
+				if distance < best || (distance == best && i < match) {
+					match = i
+					best = distance
+				}
+			}
+		}
+	}
+
+	if match == -1 {
+		return Entry{}, false
+	}
+	return g.entries[match], true
+}
+
+func key(lat, lon float64) cellKey {
+	return cellKey{
+		lat: int(math.Floor(lat / cellDegrees)),
+		lon: int(math.Floor(lon / cellDegrees)),
+	}
+}
+
+// Load reads a gazetteer file: either a GeoNames dump (tab-separated, e.g.
+// cities15000.txt) or a CSV with a header containing name, lat and lon and
+// optionally type (city or neighbourhood), city and country.
+func Load(path string) (*Gazetteer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gazetteer: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	first, err := r.Peek(4096)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("failed to read gazetteer: %w", err)
+	}
+
+	line, _, _ := strings.Cut(string(first), "\n")
+	var entries []Entry
+	if strings.Count(line, "\t") >= geonamesColumns-1 {
+		entries, err = parseGeoNames(r)
+	} else {
+		entries, err = parseCSV(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return NewGazetteer(entries), nil
+}
+
+func parseGeoNames(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < geonamesColumns {
+			continue
+		}
+		// Only populated places (feature class P); PPLX is a section of one.
+		if fields[6] != "P" {
+			continue
+		}
+
+		lat, latErr := strconv.ParseFloat(fields[4], 64)
+		lon, lonErr := strconv.ParseFloat(fields[5], 64)
+		if latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("%w: line %d: bad coordinates", ErrInvalidGazetteer, n)
+		}
+
+		kind := KindCity
+		if fields[7] == "PPLX" {
+			kind = KindNeighbourhood
+		}
+		entries = append(entries, Entry{Name: fields[1], Kind: kind, Country: fields[8], Lat: lat, Lon: lon})
+	}
+	return entries, scanner.Err()
+}
+
+func parseCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidGazetteer)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	col := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+
+	nameCol, latCol, lonCol := col("name"), col("lat", "latitude"), col("lon", "lng", "longitude")
+	if nameCol == -1 || latCol == -1 || lonCol == -1 {
+		return nil, fmt.Errorf("%w: header needs name, lat and lon columns", ErrInvalidGazetteer)
+	}
+	typeCol, cityCol, countryCol := col("type", "kind"), col("city"), col("country")
+
+	field := func(record []string, i int) string {
+		if i == -1 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []Entry
+	for n := 2; ; n++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidGazetteer, err)
+		}
+
+		lat, latErr := strconv.ParseFloat(field(record, latCol), 64)
+		lon, lonErr := strconv.ParseFloat(field(record, lonCol), 64)
+		if latErr != nil || lonErr != nil {
+			return nil, fmt.Errorf("%w: line %d: bad coordinates", ErrInvalidGazetteer, n)
+		}
+
+		kind := KindCity
+		switch strings.ToLower(field(record, typeCol)) {
+		case "", "city", "town", "village":
+		case "neighbourhood", "neighborhood", "suburb", "district":
+			kind = KindNeighbourhood
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown type %q", ErrInvalidGazetteer, n, field(record, typeCol))
+		}
+
+		entries = append(entries, Entry{
+			Name:    field(record, nameCol),
+			Kind:    kind,
+			City:    field(record, cityCol),
+			Country: field(record, countryCol),
+			Lat:     lat,
+			Lon:     lon,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,66 @@
+package geocode
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReverse(t *testing.T) {
+	for _, path := range []string{"testdata/gazetteer.csv", "testdata/geonames.txt"} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			g, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+
+			tests := []struct {
+				name     string
+				lat, lon float64
+				want     Place
+			}{
+				{"neighbourhood", -23.5610, -46.6560, Place{Neighbourhood: "Bela Vista", City: "São Paulo", Country: "BR"}},
+				{"city only", -23.5000, -46.6000, Place{City: "São Paulo", Country: "BR"}},
+				{"too far", -22.9068, -43.1729, Place{}},
+				{"no coordinates", 0, 0, Place{}},
+			}
+
+			for _, tt := range tests {
+				if got := g.Reverse(tt.lat, tt.lon); got != tt.want {
+					t.Errorf("%s: Reverse() = %+v, want %+v", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlaceString(t *testing.T) {
+	place := Place{Neighbourhood: "Bela Vista", City: "São Paulo", Country: "BR"}
+	if got := place.String(); got != "Bela Vista, São Paulo, BR" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Place{City: "Scranton"}).String(); got != "Scranton" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing columns": "name,city\nScranton,Scranton\n",
+		"bad coordinates": "name,lat,lon\nScranton,north,west\n",
+		"unknown type":    "name,lat,lon,type\nScranton,41.4,-75.6,planet\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "gazetteer.csv")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); !errors.Is(err, ErrInvalidGazetteer) {
+				t.Errorf("expected ErrInvalidGazetteer, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package locations
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"uber-extractor/internal/auth"
+)
+
+// LoadAbbreviations registers the user's address normalization files. Each
+// <locale>.json file in the directory (default ~/.ue/abbreviations) holds
+// "abbreviations", "units" and "countries" that extend or add that locale.
+func LoadAbbreviations(dir ...string) error {
+	d := getDefaultAbbreviationsDir()
+	if len(dir) > 0 && dir[0] != "" {
+		d = dir[0]
+	}
+
+	paths, err := filepath.Glob(filepath.Join(d, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list abbreviation files: %w", err)
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read abbreviations: %w", err)
+		}
+
+		var n LocaleNormalizer
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		n.Locale = strings.TrimSuffix(filepath.Base(path), ".json")
+
+		RegisterNormalizer(&n)
+		slog.Info("Loaded address abbreviations", "path", path, "locale", n.Locale, "count", len(n.Abbreviations))
+	}
+
+	return nil
+}
+
+func getDefaultAbbreviationsDir() string {
+	dir, err := auth.GetConfigDir()
+	if err != nil {
+		return "abbreviations"
+	}
+	return filepath.Join(dir, "abbreviations")
+}
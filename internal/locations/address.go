@@ -1,42 +1,334 @@
 package locations
 
-import "strings"
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
 
 const (
-	meterThreshold = 25.0
+	DefaultThreshold = 25.0
+
+	// LocaleAuto picks a locale per address from its country, falling back
+	// to DefaultLocale.
+	LocaleAuto    = "auto"
+	DefaultLocale = "pt"
+)
+
+// Normalizer turns a raw address into the form used to compare addresses.
+type Normalizer interface {
+	Normalize(address string) string
+}
+
+// LocaleNormalizer lowercases and accent-folds an address, strips punctuation
+// and unit/suite designators with the token that follows them, and expands
+// the abbreviations of its locale. Abbreviation keys are matched without
+// their trailing period; see expandsLetter for one-letter keys.
+type LocaleNormalizer struct {
+	Locale        string            `json:"-"`
+	Countries     []string          `json:"countries,omitempty"`
+	Abbreviations map[string]string `json:"abbreviations,omitempty"`
+	Units         []string          `json:"units,omitempty"`
+}
+
+var (
+	normalizersMu sync.RWMutex
+	normalizers   = map[string]*LocaleNormalizer{
+		"pt": {
+			Locale:    "pt",
+			Countries: []string{"brasil", "brazil", "portugal"},
+			Abbreviations: map[string]string{
+				"r":   "rua",
+				"av":  "avenida",
+				"dr":  "doutor",
+				"dra": "doutora",
+				"sr":  "senhor",
+				"sra": "senhora",
+				"st":  "santo",
+				"sta": "santa",
+				"vl":  "vila",
+				"jd":  "jardim",
+				"pr":  "praca",
+				"pc":  "praca",
+				"pq":  "parque",
+				"al":  "alameda",
+				"rod": "rodovia",
+				"est": "estrada",
+				"tv":  "travessa",
+			},
+			Units: []string{"ap", "apto", "apartamento", "sala", "sl", "conj", "conjunto", "bloco", "bl", "loja", "lj", "andar"},
+		},
+		"en": {
+			Locale:    "en",
+			Countries: []string{"usa", "us", "united states", "united states of america", "uk", "united kingdom", "ireland", "canada", "australia"},
+			Abbreviations: map[string]string{
+				"st":   "street",
+				"ave":  "avenue",
+				"av":   "avenue",
+				"blvd": "boulevard",
+				"rd":   "road",
+				"dr":   "drive",
+				"ln":   "lane",
+				"ct":   "court",
+				"pl":   "place",
+				"sq":   "square",
+				"hwy":  "highway",
+				"pkwy": "parkway",
+				"n":    "north",
+				"s":    "south",
+				"e":    "east",
+				"w":    "west",
+			},
+			Units: []string{"apt", "apartment", "suite", "ste", "unit", "fl", "floor", "rm", "room", "bldg"},
+		},
+		"es": {
+			Locale:    "es",
+			Countries: []string{"mexico", "espana", "spain", "argentina", "chile", "colombia", "peru"},
+			Abbreviations: map[string]string{
+				"c/":   "calle",
+				"c":    "calle",
+				"av":   "avenida",
+				"avda": "avenida",
+				"col":  "colonia",
+				"blvd": "bulevar",
+				"pza":  "plaza",
+				"pl":   "plaza",
+				"pso":  "paseo",
+				"ctra": "carretera",
+				"gral": "general",
+				"sta":  "santa",
+				"sto":  "santo",
+			},
+			Units: []string{"depto", "dpto", "departamento", "int", "interior", "piso", "puerta", "pta", "local", "desp", "despacho"},
+		},
+	}
 )
 
-var abbreviations = map[string]string{
-	"r.":   "rua",
-	"av.":  "avenida",
-	"dr.":  "doutor",
-	"dra.": "doutora",
-	"sr.":  "senhor",
-	"sra.": "senhora",
-	"st.":  "santo",
-	"sta.": "santa",
-	"vl.":  "vila",
-	"jd.":  "jardim",
-	"pr.":  "praca",
-	"pç.":  "praca",
-	"pq.":  "parque",
+// builtinNormalizers are the locales compiled into ue, without the user's
+// abbreviation files. RegisterNormalizer replaces entries rather than
+// changing them, so the shallow copy stays as it is.
+var builtinNormalizers = maps.Clone(normalizers)
+
+// RegisterNormalizer adds n under its locale, or extends the abbreviations,
+// units and countries of an already registered locale.
+func RegisterNormalizer(n *LocaleNormalizer) {
+	locale := baseLocale(n.Locale)
+
+	normalizersMu.Lock()
+	defer normalizersMu.Unlock()
+
+	existing, ok := normalizers[locale]
+	if !ok {
+		existing = &LocaleNormalizer{}
+	}
+
+	abbreviations := maps.Clone(existing.Abbreviations)
+	if abbreviations == nil {
+		abbreviations = make(map[string]string)
+	}
+	for abbr, expansion := range n.Abbreviations {
+		abbreviations[abbreviationKey(abbr)] = foldAccents(strings.ToLower(expansion))
+	}
+
+	normalizers[locale] = &LocaleNormalizer{
+		Locale:        locale,
+		Countries:     mergeWords(existing.Countries, n.Countries),
+		Abbreviations: abbreviations,
+		Units:         mergeWords(existing.Units, n.Units),
+	}
+}
+
+// GetNormalizer returns the normalizer for a locale such as "en", "es-MX" or
+// "pt_BR". An empty locale or "auto" detects the locale of each address.
+func GetNormalizer(locale string) (Normalizer, error) {
+	locale = baseLocale(locale)
+	if locale == "" || locale == LocaleAuto {
+		return autoNormalizer{}, nil
+	}
+
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+
+	n, ok := normalizers[locale]
+	if !ok {
+		return nil, fmt.Errorf("unsupported address locale: %s (expected auto or one of %s)", locale, strings.Join(normalizerLocales(), ", "))
+	}
+	return n, nil
+}
+
+// NormalizerLocales lists the registered locales.
+func NormalizerLocales() []string {
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+	return normalizerLocales()
+}
+
+func normalizerLocales() []string {
+	return slices.Sorted(maps.Keys(normalizers))
 }
 
+// NormalizeAddress normalizes an address in the locale detected from its
+// country.
 func NormalizeAddress(address string) string {
-	normalized := address
-	normalized = strings.TrimSpace(normalized)
-	normalized = strings.ToLower(normalized)
+	return autoNormalizer{}.Normalize(address)
+}
 
-	normalized = strings.ReplaceAll(normalized, ",", "")
+func (n *LocaleNormalizer) Normalize(address string) string {
+	normalized := foldAccents(strings.ToLower(strings.TrimSpace(address)))
+
+	normalized = strings.Map(func(r rune) rune {
+		switch r {
+		case ',', ';', ':', '(', ')':
+			return ' '
+		case '"', '\'', '’':
+			return -1
+		}
+		return r
+	}, normalized)
 
 	words := strings.Fields(normalized)
-	for i, word := range words {
-		if replacement, ok := abbreviations[word]; ok {
-			words[i] = replacement
+	out := make([]string, 0, len(words))
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		if strings.HasPrefix(word, "#") && hasDigit(word) {
+			continue
+		}
+
+		key := abbreviationKey(word)
+		if slices.Contains(n.Units, key) && i+1 < len(words) && (i > 0 || hasDigit(words[i+1])) {
+			i++
+			continue
+		}
+
+		if replacement, ok := n.Abbreviations[key]; ok && (len(key) > 1 || expandsLetter(words, i)) {
+			out = append(out, replacement)
+			continue
 		}
+
+		// Spanish-style "c/mayor" carries the street name in the same word.
+		if prefix, rest, ok := strings.Cut(word, "/"); ok && rest != "" && !hasDigit(prefix) {
+			if replacement, ok := n.Abbreviations[prefix+"/"]; ok {
+				out = append(out, replacement, rest)
+				continue
+			}
+		}
+
+		if trimmed := strings.TrimRight(word, "."); trimmed != "" {
+			word = trimmed
+		}
+		out = append(out, word)
 	}
 
-	normalized = strings.Join(words, " ")
+	return strings.Join(out, " ")
+}
+
+// expandsLetter reports whether the single-letter abbreviation at words[i]
+// stands for a word: when written with a period ("E."), or in the position of
+// a street type or compass point, first or right after the house number
+// ("R Augusta", "12 E 5th St"). Elsewhere it is more likely a block or unit
+// letter ("Plaza C 4").
+func expandsLetter(words []string, i int) bool {
+	return strings.HasSuffix(words[i], ".") || i == 0 || hasDigit(words[i-1])
+}
+
+type autoNormalizer struct{}
+
+func (autoNormalizer) Normalize(address string) string {
+	return detectNormalizer(address).Normalize(address)
+}
+
+// detectNormalizer matches the last words of an address against the
+// countries of each registered locale, where Uber puts the country. Trailing
+// postal codes are skipped. Commas are ignored, so stored addresses that were
+// already normalized are detected the same way as raw ones.
+func detectNormalizer(address string) *LocaleNormalizer {
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+	return detectIn(normalizers, address)
+}
 
-	return normalized
+// detectIn is detectNormalizer over the given locales.
+func detectIn(locales map[string]*LocaleNormalizer, address string) *LocaleNormalizer {
+	words := strings.Fields(strings.Map(func(r rune) rune {
+		if r == ',' || r == ';' || r == '.' {
+			return ' '
+		}
+		return r
+	}, foldAccents(strings.ToLower(address))))
+	for len(words) > 0 && hasDigit(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+
+	match, longest := locales[DefaultLocale], 0
+	for _, locale := range slices.Sorted(maps.Keys(locales)) {
+		for _, country := range locales[locale].Countries {
+			countryWords := strings.Fields(country)
+			n := len(countryWords)
+			if n > longest && n <= len(words) && slices.Equal(words[len(words)-n:], countryWords) {
+				match, longest = locales[locale], n
+			}
+		}
+	}
+	return match
+}
+
+var accentFolds = map[rune]string{
+	'á': "a", 'à': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'í': "i", 'ì': "i", 'î': "i", 'ï': "i",
+	'ó': "o", 'ò': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ú': "u", 'ù': "u", 'û': "u", 'ü': "u",
+	'ç': "c", 'ñ': "n", 'ý': "y", 'ÿ': "y",
+	'ß': "ss", 'æ': "ae", 'œ': "oe",
+	'º': "o", 'ª': "a",
+}
+
+// foldAccents replaces accented Latin letters in lowercase text with their
+// plain ASCII forms.
+func foldAccents(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if folded, ok := accentFolds[r]; ok {
+			b.WriteString(folded)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func baseLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i != -1 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+func abbreviationKey(word string) string {
+	key := foldAccents(strings.ToLower(word))
+	if trimmed := strings.TrimRight(key, "."); trimmed != "" {
+		return trimmed
+	}
+	return key
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) != -1
+}
+
+func mergeWords(a, b []string) []string {
+	merged := slices.Clone(a)
+	for _, w := range b {
+		w = abbreviationKey(w)
+		if !slices.Contains(merged, w) {
+			merged = append(merged, w)
+		}
+	}
+	return merged
 }
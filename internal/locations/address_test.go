@@ -1,6 +1,9 @@
 package locations
 
 import (
+	"maps"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -33,7 +36,7 @@ func TestNormalizeAddress(t *testing.T) {
 		{
 			name:     "multiple abbreviations",
 			input:    "R. Dr. Silva, Av. das Nações",
-			expected: "rua doutor silva avenida das nações",
+			expected: "rua doutor silva avenida das nacoes",
 		},
 		{
 			name:     "uppercase and lowercase mix",
@@ -53,7 +56,7 @@ func TestNormalizeAddress(t *testing.T) {
 		{
 			name:     "doctor abbreviation",
 			input:    "Dr. João Silva",
-			expected: "doutor joão silva",
+			expected: "doutor joao silva",
 		},
 		{
 			name:     "doutora abbreviation",
@@ -68,12 +71,12 @@ func TestNormalizeAddress(t *testing.T) {
 		{
 			name:     "square abbreviation",
 			input:    "Pç. da Sé",
-			expected: "praca da sé",
+			expected: "praca da se",
 		},
 		{
 			name:     "neighborhood abbreviation",
 			input:    "Jd. Botânico",
-			expected: "jardim botânico",
+			expected: "jardim botanico",
 		},
 		{
 			name:     "village abbreviation",
@@ -91,3 +94,98 @@ func TestNormalizeAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestLocaleNormalizers(t *testing.T) {
+	tests := []struct {
+		locale   string
+		input    string
+		expected string
+	}{
+		{"en", "1725 Slough Ave., Suite 200", "1725 slough avenue"},
+		{"en-US", "350 5th Ave. Apt 4B", "350 5th avenue"},
+		{"en", "12 N. Main St. #3", "12 north main street"},
+		{"en", "Macy's, W. 34th St.", "macys west 34th street"},
+		{"es", "C/ Mayor, 5, 2º piso 3", "calle mayor 5 2o"},
+		{"es", "c/Alcalá 10", "calle alcala 10"},
+		{"es-MX", "Av. Reforma 222, Col. Juárez, Depto 4", "avenida reforma 222 colonia juarez"},
+		{"pt_BR", "Av. Paulista, 1000, Sala 12, Bela Vista", "avenida paulista 1000 bela vista"},
+		{"pt", "R. Cônego Eugênio Leite (fundos); Apto 31", "rua conego eugenio leite fundos"},
+		{"en", "12 Oak Rd, Apt E, Austin, TX, USA", "12 oak road austin tx usa"},
+		{"en", "12 E 5th St, Suite B", "12 east 5th street"},
+		{"en", "N Main St", "north main street"},
+		{"es", "Plaza C 4, Madrid, Spain", "plaza c 4 madrid spain"},
+		{"es", "C Mayor 5", "calle mayor 5"},
+		{"pt", "Rua Augusta 100, Bloco C", "rua augusta 100"},
+		{"pt", "Sala São Paulo", "sala sao paulo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.input, func(t *testing.T) {
+			n, err := GetNormalizer(tt.locale)
+			if err != nil {
+				t.Fatalf("GetNormalizer(%q) failed: %v", tt.locale, err)
+			}
+			if got := n.Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := GetNormalizer("xx"); err == nil {
+		t.Error("expected error for unsupported locale")
+	}
+}
+
+func TestNormalizeAddressDetectsLocale(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1600 Pennsylvania Ave., Washington, DC 20500, USA", "1600 pennsylvania avenue washington dc 20500 usa"},
+		{"C/ Gran Vía 1, Madrid, España", "calle gran via 1 madrid espana"},
+		{"St. Paul Street 5, Brasil", "santo paul street 5 brasil"},
+		{"St. Paul Street 5", "santo paul street 5"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeAddress(tt.input); got != tt.expected {
+			t.Errorf("NormalizeAddress(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadAbbreviations(t *testing.T) {
+	saved := maps.Clone(normalizers)
+	t.Cleanup(func() { normalizers = saved })
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"en.json": `{"abbreviations": {"Expy.": "expressway"}, "units": ["penthouse"]}`,
+		"fr.json": `{"abbreviations": {"bd": "boulevard", "r.": "rue"}, "countries": ["france"]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := LoadAbbreviations(dir); err != nil {
+		t.Fatalf("LoadAbbreviations() failed: %v", err)
+	}
+
+	en, _ := GetNormalizer("en")
+	if got := en.Normalize("100 Lake Expy., Penthouse 2"); got != "100 lake expressway" {
+		t.Errorf("expected user abbreviations for en, got %q", got)
+	}
+	if got := en.Normalize("5 Main St."); got != "5 main street" {
+		t.Errorf("expected built-in abbreviations to be kept, got %q", got)
+	}
+
+	if got := NormalizeAddress("12 Bd. Saint-Germain, Paris, France"); got != "12 boulevard saint-germain paris france" {
+		t.Errorf("expected new locale to be detected, got %q", got)
+	}
+
+	if err := LoadAbbreviations(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("expected missing directory to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package locations
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"uber-extractor/internal/atomicfile"
+)
+
+const (
+	// MaxBackups is how many previous versions of the registry Save keeps.
+	MaxBackups = 10
+
+	// LockTimeout is how long Lock waits for another ue process to finish
+	// updating the registry.
+	LockTimeout = 10 * time.Second
+
+	backupTimeFormat = "20060102T150405.000000000Z"
+)
+
+// BackupDir is where Save keeps previous versions of the registry at path.
+func BackupDir(path ...string) string {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+	return filepath.Join(filepath.Dir(p), "backups")
+}
+
+// Backups lists the saved backups of the registry at path, oldest first.
+func Backups(path ...string) ([]string, error) {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+
+	prefix, ext := backupPrefix(p)
+	entries, err := os.ReadDir(BackupDir(p))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) {
+			backups = append(backups, filepath.Join(BackupDir(p), name))
+		}
+	}
+	// Timestamps sort lexically.
+	slices.Sort(backups)
+	return backups, nil
+}
+
+func backupPrefix(path string) (string, string) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// writeBackup stores data, the registry about to be replaced, in the backup
+// directory and returns the backup's file name.
+func writeBackup(path string, data []byte) (string, error) {
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	prefix, ext := backupPrefix(path)
+	name := prefix + time.Now().UTC().Format(backupTimeFormat) + ext
+	if err := atomicfile.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to back up registry: %w", err)
+	}
+	return name, nil
+}
+
+// rotateBackups journals the backup written by writeBackup once reg has
+// replaced the registry it holds, and removes all but the newest MaxBackups
+// backups. The registry is already saved, so failures are only logged.
+func rotateBackups(path, name string, data []byte, reg *Registry) {
+	if err := recordSnapshot(path, name, data, reg); err != nil {
+		slog.Warn("Failed to record registry snapshot", "error", err)
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		slog.Warn("Failed to list registry backups", "error", err)
+		return
+	}
+	if len(backups) <= MaxBackups {
+		return
+	}
+
+	for _, b := range backups[:len(backups)-MaxBackups] {
+		if err := os.Remove(b); err != nil {
+			slog.Warn("Failed to remove old registry backup", "path", b, "error", err)
+		}
+	}
+	if err := pruneJournal(path, backups[len(backups)-MaxBackups:]); err != nil {
+		slog.Warn("Failed to prune registry journal", "error", err)
+	}
+}
+
+// Lock takes an exclusive advisory lock on the registry at path so that a
+// Load, modify, Save cycle is not interleaved with another ue process. Save
+// does not lock by itself; callers release the lock with the returned func.
+func Lock(path ...string) (func() error, error) {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+
+	lock, err := atomicfile.Acquire(p+".lock", LockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock locations registry: %w", err)
+	}
+	return lock.Release, nil
+}
+
+// Update loads the registry at path under Lock, applies update and saves the
+// result. Nothing is saved when update returns an error.
+func Update(update func(reg *Registry) error, path ...string) error {
+	unlock, err := Lock(path...)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reg, err := Load(path...)
+	if err != nil {
+		return fmt.Errorf("failed to load locations: %w", err)
+	}
+
+	if err := update(reg); err != nil {
+		return err
+	}
+
+	if err := Save(reg, path...); err != nil {
+		return fmt.Errorf("failed to save locations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package locations
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSaveRotatesBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	for i := 1; i <= MaxBackups+3; i++ {
+		reg := &Registry{Locations: []Location{}, NextID: i}
+		if err := Save(reg, path); err != nil {
+			t.Fatalf("Save() #%d failed: %v", i, err)
+		}
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatalf("Backups() failed: %v", err)
+	}
+	if len(backups) != MaxBackups {
+		t.Fatalf("expected %d backups, got %d", MaxBackups, len(backups))
+	}
+
+	// The newest backup is the registry replaced by the last Save.
+	newest, err := Load(backups[len(backups)-1])
+	if err != nil {
+		t.Fatalf("Load() of backup failed: %v", err)
+	}
+	if newest.NextID != MaxBackups+2 {
+		t.Errorf("expected newest backup to have NextID %d, got %d", MaxBackups+2, newest.NextID)
+	}
+}
+
+func TestSaveSkipsBackupWhenUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+	reg := &Registry{Locations: []Location{}, NextID: 1}
+
+	for range 3 {
+		if err := Save(reg, path); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatalf("Backups() failed: %v", err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("expected no backups for identical saves, got %d", len(backups))
+	}
+}
+
+func TestUpdateSerializesConcurrentWriters(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("advisory locks are not implemented on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Update(func(reg *Registry) error {
+				id := fmt.Sprintf("loc-%d", reg.NextID)
+				reg.Locations = append(reg.Locations, Location{ID: id, CanonicalAddress: fmt.Sprintf("rua %d", i)})
+				reg.NextID++
+				return nil
+			}, path)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update() failed: %v", err)
+		}
+	}
+
+	reg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(reg.Locations) != writers {
+		t.Errorf("expected %d locations, got %d", writers, len(reg.Locations))
+	}
+}
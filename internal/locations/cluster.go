@@ -0,0 +1,235 @@
+package locations
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Algorithm string
+
+const (
+	AlgorithmGreedy Algorithm = "greedy"
+	AlgorithmDBSCAN Algorithm = "dbscan"
+
+	dbscanMinPoints = 2
+)
+
+type Config struct {
+	Threshold float64
+	Algorithm Algorithm
+	// Locale selects the address normalizer; see GetNormalizer.
+	Locale string
+	// MinConfidence is the fuzzy address match confidence needed to merge a
+	// visit into a nearby location outside its radius. Values above 1
+	// disable fuzzy matching.
+	MinConfidence float64
+}
+
+type Point struct {
+	Address string
+	Lat     float64
+	Lon     float64
+	Visit   Visit
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Threshold:     DefaultThreshold,
+		Algorithm:     AlgorithmGreedy,
+		Locale:        LocaleAuto,
+		MinConfidence: DefaultMinConfidence,
+	}
+}
+
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch a := Algorithm(strings.ToLower(strings.TrimSpace(s))); a {
+	case "", AlgorithmGreedy:
+		return AlgorithmGreedy, nil
+	case AlgorithmDBSCAN:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unsupported clustering algorithm: %s (expected greedy or dbscan)", s)
+	}
+}
+
+// AssignBatch resolves a location ID for every point, returning "" for points
+// without coordinates. The greedy algorithm handles points one at a time in
+// order; dbscan first matches points against existing locations and then
+// clusters the remaining ones together before creating new locations.
+func (p *Processor) AssignBatch(points []Point) []string {
+	ids := make([]string, len(points))
+
+	if p.config.Algorithm != AlgorithmDBSCAN {
+		for i, pt := range points {
+			ids[i] = p.FindOrCreateLocation(pt.Address, pt.Lat, pt.Lon, pt.Visit)
+		}
+		return ids
+	}
+
+	visits := make([]Visit, len(points))
+	duplicates := make(map[int]int)
+	seen := make(map[visitKey]int)
+
+	var pending []int
+	for i, pt := range points {
+		if pt.Lat == 0 && pt.Lon == 0 {
+			continue
+		}
+
+		if id, ok := p.recordedVisit(pt.Visit); ok {
+			ids[i] = id
+			continue
+		}
+		if key, ok := pt.Visit.key(); ok {
+			if first, ok := seen[key]; ok {
+				duplicates[i] = first
+				p.stats.Skipped++
+				continue
+			}
+			seen[key] = i
+		}
+		visits[i] = p.prepareVisit(pt.Visit, pt.Lat, pt.Lon)
+
+		addr := p.normalize(pt.Address)
+		j := p.findExistingLocation(addr, pt.Lat, pt.Lon)
+		if j == -1 {
+			pending = append(pending, i)
+			continue
+		}
+
+		p.updateLocation(j, addr, visits[i])
+		ids[i] = p.registry.Locations[j].ID
+	}
+
+	for _, cluster := range p.dbscan(points, pending) {
+		first := cluster[0]
+		id := p.createNewLocation(p.normalize(points[first].Address), visits[first])
+		j := len(p.registry.Locations) - 1
+		ids[first] = id
+
+		for _, k := range cluster[1:] {
+			p.updateLocation(j, p.normalize(points[k].Address), visits[k])
+			ids[k] = id
+		}
+	}
+
+	for i, first := range duplicates {
+		ids[i] = ids[first]
+	}
+
+	return ids
+}
+
+// dbscan groups the given point indexes into clusters. Two points are
+// neighbours when they are within the threshold, lie in the same seeded
+// radius (see SeedRadii), or share a normalized address. Noise points become single-point clusters, and clusters are
+// returned in order of their first point.
+func (p *Processor) dbscan(points []Point, indexes []int) [][]int {
+	grid := newSpatialGrid(p.config.Threshold)
+	byAddress := make(map[string][]int)
+	for n, i := range indexes {
+		grid.insert(n, points[i].Lat, points[i].Lon)
+		if addr := p.normalize(points[i].Address); addr != "" {
+			byAddress[addr] = append(byAddress[addr], n)
+		}
+	}
+
+	seedOf := make([]int, len(indexes))
+	bySeed := make(map[int][]int)
+	for n, i := range indexes {
+		seedOf[n] = p.seedAt(points[i].Lat, points[i].Lon)
+		if seedOf[n] != -1 {
+			bySeed[seedOf[n]] = append(bySeed[seedOf[n]], n)
+		}
+	}
+
+	neighbours := func(n int) []int {
+		pt := points[indexes[n]]
+
+		var result []int
+		seen := make(map[int]bool)
+		for _, m := range grid.candidates(pt.Lat, pt.Lon, p.config.Threshold) {
+			other := points[indexes[m]]
+			if HaversineDistance(pt.Lat, pt.Lon, other.Lat, other.Lon) <= p.config.Threshold {
+				result = append(result, m)
+				seen[m] = true
+			}
+		}
+		for _, m := range slices.Concat(byAddress[p.normalize(pt.Address)], bySeed[seedOf[n]]) {
+			if !seen[m] {
+				result = append(result, m)
+				seen[m] = true
+			}
+		}
+		return result
+	}
+
+	const (
+		unvisited = -1
+		noise     = -2
+	)
+
+	labels := make([]int, len(indexes))
+	for n := range labels {
+		labels[n] = unvisited
+	}
+
+	var clusters [][]int
+	for n := range indexes {
+		if labels[n] != unvisited {
+			continue
+		}
+
+		seeds := neighbours(n)
+		if len(seeds) < dbscanMinPoints {
+			labels[n] = noise
+			continue
+		}
+
+		c := len(clusters)
+		labels[n] = c
+		clusters = append(clusters, []int{n})
+
+		for len(seeds) > 0 {
+			m := seeds[0]
+			seeds = seeds[1:]
+
+			switch labels[m] {
+			case noise:
+				labels[m] = c
+				clusters[c] = append(clusters[c], m)
+				continue
+			case unvisited:
+			default:
+				continue
+			}
+
+			labels[m] = c
+			clusters[c] = append(clusters[c], m)
+
+			if next := neighbours(m); len(next) >= dbscanMinPoints {
+				seeds = append(seeds, next...)
+			}
+		}
+	}
+
+	for n := range indexes {
+		if labels[n] == noise {
+			clusters = append(clusters, []int{n})
+		}
+	}
+
+	result := make([][]int, len(clusters))
+	for c, members := range clusters {
+		result[c] = make([]int, len(members))
+		for k, n := range members {
+			result[c][k] = indexes[n]
+		}
+		slices.Sort(result[c])
+	}
+	slices.SortFunc(result, func(a, b []int) int {
+		return a[0] - b[0]
+	})
+	return result
+}
@@ -0,0 +1,151 @@
+package locations
+
+import (
+	"fmt"
+	"testing"
+)
+
+// terminalPoints returns points 20 m apart along a line, so each is within the
+// default threshold of its neighbours but the ends are 100 m apart.
+func terminalPoints() []Point {
+	const step = 20.0 / metersPerDegree
+
+	points := make([]Point, 6)
+	for i := range points {
+		points[i] = Point{
+			Address: "aeroporto de guarulhos - terminal " + string(rune('1'+i)),
+			Lat:     -23.4356,
+			Lon:     -46.4731 + float64(i)*step/0.9175,
+		}
+	}
+	return points
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Algorithm
+		wantErr bool
+	}{
+		{input: "", want: AlgorithmGreedy},
+		{input: "greedy", want: AlgorithmGreedy},
+		{input: "DBSCAN", want: AlgorithmDBSCAN},
+		{input: "kmeans", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseAlgorithm(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAlgorithm(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAlgorithm(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignBatchGreedy(t *testing.T) {
+	points := []Point{
+		{Address: "123 Test Street", Lat: 41.4089, Lon: -75.6624},
+		{Address: "Invalid Location", Lat: 0, Lon: 0},
+		{Address: "123 test street", Lat: 41.4089, Lon: -75.6624},
+		{Address: "456 Far Away Street", Lat: 41.4500, Lon: -75.6200},
+	}
+
+	processor := NewProcessor(nil)
+	ids := processor.AssignBatch(points)
+
+	want := []string{"loc-1", "", "loc-1", "loc-2"}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("point %d: expected %q, got %q", i, want[i], ids[i])
+		}
+	}
+}
+
+func TestAssignBatchDBSCAN(t *testing.T) {
+	registry := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "1725 slough avenue", AddressVariants: []string{}, AvgLat: 41.4089, AvgLon: -75.6624, VisitCount: 3},
+		},
+		NextID: 2,
+	}
+
+	points := append(terminalPoints(),
+		Point{Address: "1725 Slough Avenue", Lat: 41.4089, Lon: -75.6624},
+		Point{Address: "Lonely Road 1", Lat: -23.0000, Lon: -46.0000},
+	)
+
+	processor := NewProcessorWithConfig(registry, Config{Algorithm: AlgorithmDBSCAN})
+	ids := processor.AssignBatch(points)
+
+	for i := 0; i < 6; i++ {
+		if ids[i] != "loc-2" {
+			t.Errorf("terminal point %d: expected loc-2, got %q", i, ids[i])
+		}
+	}
+
+	if ids[6] != "loc-1" {
+		t.Errorf("expected existing location to be matched, got %q", ids[6])
+	}
+
+	if ids[7] != "loc-3" {
+		t.Errorf("expected noise point to become its own location, got %q", ids[7])
+	}
+
+	terminal, _ := registry.Find("loc-2")
+	if terminal.VisitCount != 6 {
+		t.Errorf("expected 6 visits at the terminal, got %d", terminal.VisitCount)
+	}
+
+	if len(terminal.AddressVariants) != 5 {
+		t.Errorf("expected 5 address variants, got %d", len(terminal.AddressVariants))
+	}
+}
+
+func TestAssignBatchGreedyFragmentsTerminal(t *testing.T) {
+	processor := NewProcessor(nil)
+	processor.AssignBatch(terminalPoints())
+
+	if len(processor.Registry().Locations) < 2 {
+		t.Errorf("expected greedy clustering to fragment the terminal, got %d location", len(processor.Registry().Locations))
+	}
+
+	dbscan := NewProcessorWithConfig(nil, Config{Algorithm: AlgorithmDBSCAN})
+	dbscan.AssignBatch(terminalPoints())
+
+	if len(dbscan.Registry().Locations) != 1 {
+		t.Errorf("expected dbscan to produce 1 location, got %d", len(dbscan.Registry().Locations))
+	}
+}
+
+func TestAssignBatchDBSCANSkipsRecordedVisits(t *testing.T) {
+	points := terminalPoints()
+	for i := range points {
+		points[i].Visit = Visit{TripUUID: fmt.Sprintf("trip-%d", i), Role: VisitPickup}
+	}
+
+	// The same trip appears twice within the first batch and the whole
+	// batch is ingested again afterwards.
+	points = append(points, points[0])
+
+	registry := &Registry{NextID: 1}
+	want := []IngestStats{{Recorded: 6, Skipped: 1}, {Recorded: 0, Skipped: 7}}
+	for run := range want {
+		processor := NewProcessorWithConfig(registry, Config{Algorithm: AlgorithmDBSCAN})
+		for i, id := range processor.AssignBatch(points) {
+			if id != "loc-1" {
+				t.Errorf("run %d, point %d: expected loc-1, got %q", run, i, id)
+			}
+		}
+		if stats := processor.Stats(); stats != want[run] {
+			t.Errorf("run %d: expected stats %+v, got %+v", run, want[run], stats)
+		}
+	}
+
+	if len(registry.Locations) != 1 || registry.Locations[0].VisitCount != 6 {
+		t.Errorf("expected a single location with 6 visits, got %+v", registry.Locations)
+	}
+}
@@ -0,0 +1,159 @@
+package locations
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	ErrSameLocation    = errors.New("cannot merge a location with itself")
+	ErrVariantNotFound = errors.New("address variant not found")
+	ErrNoVisits        = errors.New("no visits to split")
+)
+
+func (r *Registry) Merge(targetRef, sourceRef string) (*Location, error) {
+	target, err := r.Find(targetRef)
+	if err != nil {
+		return nil, err
+	}
+	source, err := r.Find(sourceRef)
+	if err != nil {
+		return nil, err
+	}
+	if target.ID == source.ID {
+		return nil, fmt.Errorf("%w: %s", ErrSameLocation, target.ID)
+	}
+
+	merged := *target
+	merged.VisitCount = target.VisitCount + source.VisitCount
+	if merged.VisitCount > 0 {
+		tw := float64(target.VisitCount) / float64(merged.VisitCount)
+		sw := float64(source.VisitCount) / float64(merged.VisitCount)
+		merged.AvgLat = target.AvgLat*tw + source.AvgLat*sw
+		merged.AvgLon = target.AvgLon*tw + source.AvgLon*sw
+	}
+
+	if source.VisitCount > target.VisitCount {
+		merged.CanonicalAddress = source.CanonicalAddress
+	}
+
+	merged.AddressVariants = []string{}
+	for _, addr := range slices.Concat([]string{target.CanonicalAddress}, target.AddressVariants, []string{source.CanonicalAddress}, source.AddressVariants) {
+		if addr != merged.CanonicalAddress && !slices.Contains(merged.AddressVariants, addr) {
+			merged.AddressVariants = append(merged.AddressVariants, addr)
+		}
+	}
+
+	merged.FirstSeen = earliest(target.FirstSeen, source.FirstSeen)
+	merged.LastSeen = latest(target.LastSeen, source.LastSeen)
+	merged.Visits = slices.Concat(target.Visits, source.Visits)
+	sortVisits(merged.Visits)
+
+	if merged.Label == "" {
+		merged.Label = source.Label
+	}
+
+	sourceID := source.ID
+	*target = merged
+
+	r.Locations = slices.DeleteFunc(r.Locations, func(l Location) bool {
+		return l.ID == sourceID
+	})
+	r.addAlias(sourceID, merged.ID)
+
+	return r.Find(merged.ID)
+}
+
+// Split moves an address variant of a location, normalized with normalizer,
+// into a new location together with the given visits to it. The visits carry
+// the new location's coordinates, visit count and first/last seen times, so
+// at least one is required.
+func (r *Registry) Split(ref, variant string, normalizer Normalizer, visits []Visit) (*Location, error) {
+	loc, err := r.Find(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	variant = normalizer.Normalize(variant)
+	if !slices.Contains(loc.AddressVariants, variant) {
+		return nil, fmt.Errorf("%w: %q in %s", ErrVariantNotFound, variant, loc.ID)
+	}
+	if len(visits) == 0 {
+		return nil, fmt.Errorf("%w: %q in %s", ErrNoVisits, variant, loc.ID)
+	}
+
+	newLoc := Location{
+		ID:               fmt.Sprintf("loc-%d", r.NextID),
+		CanonicalAddress: variant,
+		AddressVariants:  []string{},
+	}
+	r.NextID++
+
+	loc.AddressVariants = slices.DeleteFunc(loc.AddressVariants, func(a string) bool {
+		return a == variant
+	})
+
+	var sumLat, sumLon float64
+	moved := make(map[visitKey]bool)
+	for _, v := range visits {
+		sumLat += v.Lat
+		sumLon += v.Lon
+		newLoc.FirstSeen = earliest(newLoc.FirstSeen, v.Time)
+		newLoc.LastSeen = latest(newLoc.LastSeen, v.Time)
+		if key, ok := v.key(); ok {
+			moved[key] = true
+		}
+	}
+	loc.Visits = slices.DeleteFunc(loc.Visits, func(v Visit) bool {
+		key, ok := v.key()
+		if ok && moved[key] {
+			newLoc.Visits = append(newLoc.Visits, v)
+			return true
+		}
+		return false
+	})
+
+	n := len(visits)
+	newLoc.VisitCount = n
+	newLoc.AvgLat = sumLat / float64(n)
+	newLoc.AvgLon = sumLon / float64(n)
+
+	if remaining := loc.VisitCount - n; remaining > 0 {
+		loc.AvgLat = (loc.AvgLat*float64(loc.VisitCount) - sumLat) / float64(remaining)
+		loc.AvgLon = (loc.AvgLon*float64(loc.VisitCount) - sumLon) / float64(remaining)
+		loc.VisitCount = remaining
+	} else {
+		loc.VisitCount = 0
+	}
+
+	r.Locations = append(r.Locations, newLoc)
+	return &r.Locations[len(r.Locations)-1], nil
+}
+
+func (r *Registry) addAlias(from, to string) {
+	if r.Aliases == nil {
+		r.Aliases = make(map[string]string)
+	}
+	for old, target := range r.Aliases {
+		if target == from {
+			r.Aliases[old] = to
+		}
+	}
+	r.Aliases[from] = to
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,177 @@
+package locations
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func editRegistry() *Registry {
+	return &Registry{
+		Locations: []Location{
+			{
+				ID:               "loc-3",
+				Label:            "office",
+				CanonicalAddress: "1725 slough avenue",
+				AddressVariants:  []string{"dunder mifflin"},
+				AvgLat:           41.4089,
+				AvgLon:           -75.6624,
+				VisitCount:       3,
+				FirstSeen:        time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				LastSeen:         time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				ID:               "loc-7",
+				CanonicalAddress: "1725 slough avenue - back entrance",
+				AddressVariants:  []string{"dunder mifflin", "loading dock"},
+				AvgLat:           41.4093,
+				AvgLon:           -75.6620,
+				VisitCount:       1,
+				FirstSeen:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				LastSeen:         time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		NextID: 8,
+	}
+}
+
+func TestRegistryMerge(t *testing.T) {
+	registry := editRegistry()
+
+	merged, err := registry.Merge("office", "loc-7")
+	if err != nil {
+		t.Fatalf("Merge() failed: %v", err)
+	}
+
+	if len(registry.Locations) != 1 {
+		t.Fatalf("expected 1 location after merge, got %d", len(registry.Locations))
+	}
+
+	if merged.ID != "loc-3" || merged.Label != "office" {
+		t.Errorf("expected loc-3 (office) to survive, got %s (%s)", merged.ID, merged.Label)
+	}
+
+	if merged.VisitCount != 4 {
+		t.Errorf("expected visit count 4, got %d", merged.VisitCount)
+	}
+
+	wantLat := (41.4089*3 + 41.4093) / 4
+	if math.Abs(merged.AvgLat-wantLat) > 1e-9 {
+		t.Errorf("expected weighted latitude %v, got %v", wantLat, merged.AvgLat)
+	}
+
+	wantVariants := []string{"dunder mifflin", "1725 slough avenue - back entrance", "loading dock"}
+	if len(merged.AddressVariants) != len(wantVariants) {
+		t.Fatalf("expected variants %v, got %v", wantVariants, merged.AddressVariants)
+	}
+	for i, v := range wantVariants {
+		if merged.AddressVariants[i] != v {
+			t.Errorf("variant %d: expected %q, got %q", i, v, merged.AddressVariants[i])
+		}
+	}
+
+	if !merged.FirstSeen.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected earliest first seen, got %v", merged.FirstSeen)
+	}
+
+	if !merged.LastSeen.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected latest last seen, got %v", merged.LastSeen)
+	}
+
+	loc, err := registry.Find("loc-7")
+	if err != nil {
+		t.Fatalf("expected merged ID to resolve, got %v", err)
+	}
+	if loc.ID != "loc-3" {
+		t.Errorf("expected loc-7 to resolve to loc-3, got %s", loc.ID)
+	}
+
+	if _, err := registry.Merge("loc-3", "office"); !errors.Is(err, ErrSameLocation) {
+		t.Errorf("expected ErrSameLocation, got %v", err)
+	}
+}
+
+func TestRegistrySplit(t *testing.T) {
+	t.Run("split with visits", func(t *testing.T) {
+		registry := editRegistry()
+
+		visits := []Visit{
+			{TripUUID: "trip-2", Role: VisitDropoff, Lat: 41.4100, Lon: -75.6600, Time: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)},
+		}
+		office, _ := registry.Find("office")
+		office.Visits = []Visit{{TripUUID: "trip-1", Role: VisitPickup}, visits[0]}
+
+		newLoc, err := registry.Split("office", "Dunder Mifflin", autoNormalizer{}, visits)
+		if err != nil {
+			t.Fatalf("Split() failed: %v", err)
+		}
+
+		if newLoc.ID != "loc-8" || registry.NextID != 9 {
+			t.Errorf("expected new location loc-8 and NextID 9, got %s and %d", newLoc.ID, registry.NextID)
+		}
+
+		if newLoc.CanonicalAddress != "dunder mifflin" {
+			t.Errorf("expected canonical address of variant, got %q", newLoc.CanonicalAddress)
+		}
+
+		if newLoc.VisitCount != 1 || newLoc.AvgLat != 41.4100 {
+			t.Errorf("expected stats from visits, got %d visits at %v", newLoc.VisitCount, newLoc.AvgLat)
+		}
+
+		orig, _ := registry.Find("loc-3")
+		if orig.VisitCount != 2 {
+			t.Errorf("expected original visit count 2, got %d", orig.VisitCount)
+		}
+		if len(orig.AddressVariants) != 0 {
+			t.Errorf("expected variant to be removed, got %v", orig.AddressVariants)
+		}
+		if len(orig.Visits) != 1 || len(newLoc.Visits) != 1 || newLoc.Visits[0].TripUUID != "trip-2" {
+			t.Errorf("expected visit log to move with the split, got %+v and %+v", orig.Visits, newLoc.Visits)
+		}
+
+		wantLat := (41.4089*3 - 41.4100) / 2
+		if math.Abs(orig.AvgLat-wantLat) > 1e-9 {
+			t.Errorf("expected original latitude %v, got %v", wantLat, orig.AvgLat)
+		}
+	})
+
+	t.Run("unknown variant", func(t *testing.T) {
+		registry := editRegistry()
+
+		_, err := registry.Split("loc-3", "scranton business park", autoNormalizer{}, nil)
+		if !errors.Is(err, ErrVariantNotFound) {
+			t.Errorf("expected ErrVariantNotFound, got %v", err)
+		}
+	})
+
+	t.Run("no visits", func(t *testing.T) {
+		registry := editRegistry()
+
+		_, err := registry.Split("office", "dunder mifflin", autoNormalizer{}, nil)
+		if !errors.Is(err, ErrNoVisits) {
+			t.Errorf("expected ErrNoVisits, got %v", err)
+		}
+
+		orig, _ := registry.Find("loc-3")
+		if len(registry.Locations) != 2 || registry.NextID != 8 || len(orig.AddressVariants) != 1 {
+			t.Errorf("expected registry to be left unchanged, got %d locations, NextID %d, variants %v", len(registry.Locations), registry.NextID, orig.AddressVariants)
+		}
+	})
+
+	t.Run("configured locale", func(t *testing.T) {
+		registry := editRegistry()
+		office, _ := registry.Find("office")
+		office.AddressVariants = []string{"avenida paulista 1000"}
+
+		en, _ := GetNormalizer("en")
+		if _, err := registry.Split("office", "Av. Paulista 1000", en, []Visit{{TripUUID: "trip-2"}}); !errors.Is(err, ErrVariantNotFound) {
+			t.Errorf("expected the en normalizer to leave the variant unmatched, got %v", err)
+		}
+
+		pt, _ := GetNormalizer("pt")
+		if _, err := registry.Split("office", "Av. Paulista 1000", pt, []Visit{{TripUUID: "trip-2"}}); err != nil {
+			t.Errorf("Split() with the pt normalizer failed: %v", err)
+		}
+	})
+}
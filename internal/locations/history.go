@@ -0,0 +1,255 @@
+package locations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"uber-extractor/internal/atomicfile"
+)
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot is a journal entry for one change to the registry. Its backup
+// file holds the registry as it was before the change, so restoring a
+// snapshot undoes that change and everything after it.
+type Snapshot struct {
+	ID      int           `json:"id"`
+	Time    time.Time     `json:"time"`
+	User    string        `json:"user,omitempty"`
+	Command string        `json:"command"`
+	Changes ChangeSummary `json:"changes"`
+	File    string        `json:"file"`
+}
+
+// ChangeSummary counts how a save changed the registry.
+type ChangeSummary struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+	Visits  int `json:"visits"`
+}
+
+// String formats the summary as e.g. "+2 -1 ~3 locations, +14 visits".
+func (c ChangeSummary) String() string {
+	var parts []string
+	if c.Added > 0 || c.Removed > 0 || c.Changed > 0 {
+		var counts []string
+		if c.Added > 0 {
+			counts = append(counts, fmt.Sprintf("+%d", c.Added))
+		}
+		if c.Removed > 0 {
+			counts = append(counts, fmt.Sprintf("-%d", c.Removed))
+		}
+		if c.Changed > 0 {
+			counts = append(counts, fmt.Sprintf("~%d", c.Changed))
+		}
+		parts = append(parts, strings.Join(counts, " ")+" locations")
+	}
+	if c.Visits != 0 {
+		parts = append(parts, fmt.Sprintf("%+d visits", c.Visits))
+	}
+	if len(parts) == 0 {
+		return "no location changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+type journal struct {
+	NextID    int        `json:"nextID"`
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+func journalPath(path string) string {
+	base := filepath.Base(path)
+	return filepath.Join(BackupDir(path), strings.TrimSuffix(base, filepath.Ext(base))+".journal.json")
+}
+
+func loadJournal(path string) (*journal, error) {
+	data, err := os.ReadFile(journalPath(path))
+	if os.IsNotExist(err) {
+		return &journal{NextID: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal journal: %w", err)
+	}
+	return &j, nil
+}
+
+func saveJournal(path string, j *journal) error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal: %w", err)
+	}
+	if err := atomicfile.WriteFile(journalPath(path), data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// History returns the journal of the registry at path, newest first. Only
+// snapshots whose backup is still kept are listed, since the others cannot
+// be restored.
+func History(path ...string) ([]Snapshot, error) {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+
+	j, err := loadJournal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := slices.DeleteFunc(slices.Clone(j.Snapshots), func(s Snapshot) bool {
+		_, err := os.Stat(filepath.Join(BackupDir(p), s.File))
+		return err != nil
+	})
+	slices.Reverse(snapshots)
+	return snapshots, nil
+}
+
+// Restore replaces the registry at path with the backup of snapshot id,
+// under Lock. The restore is itself journaled, so it can be undone.
+func Restore(id int, path ...string) (Snapshot, error) {
+	p := getDefaultPath()
+	if len(path) > 0 && path[0] != "" {
+		p = path[0]
+	}
+
+	unlock, err := Lock(p)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer unlock()
+
+	j, err := loadJournal(p)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	i := slices.IndexFunc(j.Snapshots, func(s Snapshot) bool { return s.ID == id })
+	if i == -1 {
+		return Snapshot{}, fmt.Errorf("%w: %d", ErrSnapshotNotFound, id)
+	}
+	snapshot := j.Snapshots[i]
+
+	backup := filepath.Join(BackupDir(p), snapshot.File)
+	if _, err := os.Stat(backup); err != nil {
+		return Snapshot{}, fmt.Errorf("%w: %d: backup %s is missing", ErrSnapshotNotFound, id, snapshot.File)
+	}
+
+	reg, err := Load(backup)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("failed to load snapshot %d: %w", id, err)
+	}
+
+	if err := Save(reg, p); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to save locations: %w", err)
+	}
+	return snapshot, nil
+}
+
+// recordSnapshot journals a save that replaced old with reg, whose previous
+// content was backed up to file.
+func recordSnapshot(path, file string, old []byte, reg *Registry) error {
+	j, err := loadJournal(path)
+	if err != nil {
+		return err
+	}
+
+	j.Snapshots = append(j.Snapshots, Snapshot{
+		ID:      j.NextID,
+		Time:    time.Now(),
+		User:    currentUser(),
+		Command: commandLine(),
+		Changes: summarizeChanges(old, reg),
+		File:    file,
+	})
+	j.NextID++
+
+	return saveJournal(path, j)
+}
+
+// pruneJournal drops snapshots whose backup was rotated away.
+func pruneJournal(path string, backups []string) error {
+	j, err := loadJournal(path)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(backups))
+	for _, b := range backups {
+		kept[filepath.Base(b)] = true
+	}
+
+	n := len(j.Snapshots)
+	j.Snapshots = slices.DeleteFunc(j.Snapshots, func(s Snapshot) bool { return !kept[s.File] })
+	if len(j.Snapshots) == n {
+		return nil
+	}
+	return saveJournal(path, j)
+}
+
+// summarizeChanges compares the registry JSON being replaced with reg. An
+// unreadable old registry counts every location as added.
+func summarizeChanges(old []byte, reg *Registry) ChangeSummary {
+	var prev Registry
+	if data, _, err := migrate(old); err == nil {
+		json.Unmarshal(data, &prev)
+	}
+
+	before := make(map[string]Location, len(prev.Locations))
+	var summary ChangeSummary
+	for _, loc := range prev.Locations {
+		before[loc.ID] = loc
+		summary.Visits -= loc.VisitCount
+	}
+
+	for _, loc := range reg.Locations {
+		summary.Visits += loc.VisitCount
+
+		prevLoc, ok := before[loc.ID]
+		if !ok {
+			summary.Added++
+			continue
+		}
+		delete(before, loc.ID)
+
+		a, _ := json.Marshal(prevLoc)
+		b, _ := json.Marshal(loc)
+		if string(a) != string(b) {
+			summary.Changed++
+		}
+	}
+	summary.Removed = len(before)
+	return summary
+}
+
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return u.Username
+}
+
+func commandLine() string {
+	if len(os.Args) == 0 {
+		return ""
+	}
+	args := slices.Clone(os.Args)
+	args[0] = filepath.Base(args[0])
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,148 @@
+package locations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistoryRecordsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	reg := &Registry{Locations: []Location{
+		{ID: "loc-1", CanonicalAddress: "rua augusta 100", VisitCount: 2},
+		{ID: "loc-2", CanonicalAddress: "avenida paulista 1000", VisitCount: 1},
+	}, NextID: 3}
+	if err := Save(reg, path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if _, err := reg.Delete("loc-2"); err != nil {
+		t.Fatal(err)
+	}
+	reg.Locations[0].VisitCount = 5
+	reg.Locations = append(reg.Locations, Location{ID: "loc-3", CanonicalAddress: "rua da consolacao 200", VisitCount: 1})
+	reg.NextID = 4
+	if err := Save(reg, path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	snapshots, err := History(path)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snapshots))
+	}
+
+	want := ChangeSummary{Added: 1, Removed: 1, Changed: 1, Visits: 3}
+	if snapshots[0].Changes != want {
+		t.Errorf("expected changes %+v, got %+v", want, snapshots[0].Changes)
+	}
+	if got := snapshots[0].Changes.String(); got != "+1 -1 ~1 locations, +3 visits" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if snapshots[0].Command == "" {
+		t.Error("expected the command to be recorded")
+	}
+}
+
+func TestRestoreRollsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	reg := &Registry{Locations: []Location{{ID: "loc-1", Label: "home"}}, NextID: 2}
+	if err := Save(reg, path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	reg.Locations[0].Label = "office"
+	if err := Save(reg, path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	snapshots, err := History(path)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if _, err := Restore(snapshots[0].ID, path); err != nil {
+		t.Fatalf("Restore() failed: %v", err)
+	}
+
+	restored, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if restored.Locations[0].Label != "home" {
+		t.Errorf("expected label home after restore, got %q", restored.Locations[0].Label)
+	}
+
+	// The restore is journaled too, so it can be undone.
+	snapshots, err = History(path)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Changes.Changed != 1 {
+		t.Errorf("expected the restore to be recorded, got %+v", snapshots)
+	}
+}
+
+func TestRestoreUnknownSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	if _, err := Restore(42, path); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+}
+
+func TestHistoryPrunesRotatedSnapshots(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	for i := 1; i <= MaxBackups+3; i++ {
+		if err := Save(&Registry{Locations: []Location{}, NextID: i}, path); err != nil {
+			t.Fatalf("Save() #%d failed: %v", i, err)
+		}
+	}
+
+	snapshots, err := History(path)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(snapshots) != MaxBackups {
+		t.Fatalf("expected %d snapshots, got %d", MaxBackups, len(snapshots))
+	}
+	if snapshots[0].ID != MaxBackups+2 {
+		t.Errorf("expected newest snapshot %d, got %d", MaxBackups+2, snapshots[0].ID)
+	}
+}
+
+func TestHistorySkipsMissingBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locations.json")
+
+	reg := &Registry{Locations: []Location{{ID: "loc-1", Label: "home"}}, NextID: 2}
+	for _, label := range []string{"home", "office", "gym"} {
+		reg.Locations[0].Label = label
+		if err := Save(reg, path); err != nil {
+			t.Fatalf("Save() failed: %v", err)
+		}
+	}
+
+	snapshots, err := History(path)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+	}
+
+	if err := os.Remove(filepath.Join(BackupDir(path), snapshots[1].File)); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, err := History(path)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(kept) != 1 || kept[0].ID != snapshots[0].ID {
+		t.Errorf("expected only snapshot %d with its backup, got %+v", snapshots[0].ID, kept)
+	}
+}
@@ -0,0 +1,117 @@
+package locations
+
+import (
+	"math"
+	"slices"
+)
+
+const metersPerDegree = 111320.0
+
+type cellKey struct {
+	lat int
+	lon int
+}
+
+// spatialGrid buckets location indexes into cells of roughly equal size in
+// degrees, so nearby candidates can be found without scanning the registry.
+type spatialGrid struct {
+	cellDeg float64
+	cells   map[cellKey][]int
+}
+
+func newSpatialGrid(cellMeters float64) *spatialGrid {
+	return &spatialGrid{
+		cellDeg: cellMeters / metersPerDegree,
+		cells:   make(map[cellKey][]int),
+	}
+}
+
+func (g *spatialGrid) key(lat, lon float64) cellKey {
+	return cellKey{
+		lat: int(math.Floor(lat / g.cellDeg)),
+		lon: int(math.Floor(lon / g.cellDeg)),
+	}
+}
+
+func (g *spatialGrid) insert(i int, lat, lon float64) {
+	k := g.key(lat, lon)
+	g.cells[k] = append(g.cells[k], i)
+}
+
+func (g *spatialGrid) remove(i int, lat, lon float64) {
+	k := g.key(lat, lon)
+	g.cells[k] = slices.DeleteFunc(g.cells[k], func(j int) bool { return j == i })
+	if len(g.cells[k]) == 0 {
+		delete(g.cells, k)
+	}
+}
+
+func (g *spatialGrid) move(i int, oldLat, oldLon, lat, lon float64) {
+	if g.key(oldLat, oldLon) == g.key(lat, lon) {
+		return
+	}
+	g.remove(i, oldLat, oldLon)
+	g.insert(i, lat, lon)
+}
+
+// candidates returns the indexes in every cell that may hold a point within
+// radius meters of (lat, lon). Callers still need to check the exact distance.
+// Near the poles, or for a radius spanning more cells than are occupied, the
+// occupied cells are filtered instead of visiting every cell in range.
+func (g *spatialGrid) candidates(lat, lon, radius float64) []int {
+	latCells := int(math.Ceil(radius / (metersPerDegree * g.cellDeg)))
+	center := g.key(lat, lon)
+
+	lonCells := math.Inf(1)
+	if cos := math.Cos(toRadians(lat)); cos > 0 {
+		lonCells = math.Ceil(radius / (metersPerDegree * cos * g.cellDeg))
+	}
+
+	var result []int
+	if (2*float64(latCells)+1)*(2*lonCells+1) > float64(len(g.cells)) {
+		for k, indexes := range g.cells {
+			if abs(k.lat-center.lat) <= latCells && float64(abs(k.lon-center.lon)) <= lonCells {
+				result = append(result, indexes...)
+			}
+		}
+		slices.Sort(result)
+		return result
+	}
+
+	for dlat := -latCells; dlat <= latCells; dlat++ {
+		for dlon := -int(lonCells); dlon <= int(lonCells); dlon++ {
+			result = append(result, g.cells[cellKey{lat: center.lat + dlat, lon: center.lon + dlon}]...)
+		}
+	}
+	return result
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+type addressIndex map[string][]int
+
+func (idx addressIndex) add(address string, i int) {
+	if address == "" || slices.Contains(idx[address], i) {
+		return
+	}
+	idx[address] = append(idx[address], i)
+}
+
+func (idx addressIndex) remove(address string, i int) {
+	idx[address] = slices.DeleteFunc(idx[address], func(j int) bool { return j == i })
+	if len(idx[address]) == 0 {
+		delete(idx, address)
+	}
+}
+
+func (idx addressIndex) first(address string) int {
+	if len(idx[address]) == 0 {
+		return -1
+	}
+	return slices.Min(idx[address])
+}
@@ -0,0 +1,86 @@
+package locations
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestSpatialGridCandidates(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, baseLat := range []float64{-23.55, 0, 41.40, 64.15} {
+		grid := newSpatialGrid(DefaultThreshold)
+
+		points := make([][2]float64, 2000)
+		for i := range points {
+			points[i] = [2]float64{
+				baseLat + (rng.Float64()-0.5)*0.01,
+				-46.63 + (rng.Float64()-0.5)*0.01,
+			}
+			grid.insert(i, points[i][0], points[i][1])
+		}
+
+		for q := 0; q < 200; q++ {
+			lat := baseLat + (rng.Float64()-0.5)*0.01
+			lon := -46.63 + (rng.Float64()-0.5)*0.01
+
+			candidates := grid.candidates(lat, lon, DefaultThreshold)
+			for i, pt := range points {
+				if HaversineDistance(lat, lon, pt[0], pt[1]) <= DefaultThreshold && !slices.Contains(candidates, i) {
+					t.Fatalf("lat %v: point %d within threshold missing from candidates", baseLat, i)
+				}
+			}
+		}
+	}
+}
+
+func TestSpatialGridCandidatesWideRadius(t *testing.T) {
+	grid := newSpatialGrid(DefaultThreshold)
+	grid.insert(0, 0, 0.05)
+	grid.insert(1, 89.9, 120)
+	grid.insert(2, 10, 10)
+
+	if got := grid.candidates(0, 0, 6000); !slices.Contains(got, 0) || slices.Contains(got, 2) {
+		t.Errorf("expected only the point 5.6 km away within 6 km, got %v", got)
+	}
+	if got := grid.candidates(89.95, -60, 20000); !slices.Contains(got, 1) {
+		t.Errorf("expected the point across the pole within 20 km, got %v", got)
+	}
+}
+
+func TestSpatialGridMove(t *testing.T) {
+	grid := newSpatialGrid(DefaultThreshold)
+	grid.insert(0, 41.4089, -75.6624)
+
+	grid.move(0, 41.4089, -75.6624, 41.4500, -75.6200)
+
+	if slices.Contains(grid.candidates(41.4089, -75.6624, DefaultThreshold), 0) {
+		t.Error("expected location to be removed from its old cell")
+	}
+
+	if !slices.Contains(grid.candidates(41.4500, -75.6200, DefaultThreshold), 0) {
+		t.Error("expected location to be found in its new cell")
+	}
+}
+
+func TestAddressIndex(t *testing.T) {
+	idx := make(addressIndex)
+	idx.add("rua teste 123", 4)
+	idx.add("rua teste 123", 2)
+	idx.add("rua teste 123", 2)
+
+	if got := idx.first("rua teste 123"); got != 2 {
+		t.Errorf("expected lowest index 2, got %d", got)
+	}
+
+	idx.remove("rua teste 123", 2)
+	if got := idx.first("rua teste 123"); got != 4 {
+		t.Errorf("expected index 4 after removal, got %d", got)
+	}
+
+	idx.remove("rua teste 123", 4)
+	if got := idx.first("rua teste 123"); got != -1 {
+		t.Errorf("expected -1 for unknown address, got %d", got)
+	}
+}
@@ -0,0 +1,141 @@
+package locations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"uber-extractor/internal/atomicfile"
+)
+
+// CurrentVersion is the registry schema version written by this build.
+// Registries without a version field are version 1.
+const CurrentVersion = 3
+
+var ErrNewerRegistry = errors.New("registry was written by a newer version of ue")
+
+// migrations[i] upgrades the JSON of a version i+1 registry to version i+2.
+var migrations = []func(data []byte) ([]byte, error){
+	migrateV1ToV2,
+	migrateV2ToV3,
+}
+
+func registryVersion(data []byte) (int, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.Version == 0 {
+		return 1, nil
+	}
+	return header.Version, nil
+}
+
+// migrate upgrades registry JSON to CurrentVersion and returns it with the
+// version it started at.
+func migrate(data []byte) ([]byte, int, error) {
+	version, err := registryVersion(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > CurrentVersion {
+		return nil, version, fmt.Errorf("%w: version %d, this build supports up to %d; please upgrade ue", ErrNewerRegistry, version, CurrentVersion)
+	}
+
+	for v := version; v < CurrentVersion; v++ {
+		data, err = migrations[v-1](data)
+		if err != nil {
+			return nil, version, fmt.Errorf("failed to migrate registry from version %d: %w", v, err)
+		}
+	}
+	return data, version, nil
+}
+
+// backupPath is where Save keeps the file of an older registry version
+// before overwriting it.
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// checkExisting refuses to overwrite a registry written by a newer ue and
+// backs up one written by an older ue. An existing backup is kept.
+func checkExisting(path string, data []byte) error {
+	version, err := registryVersion(data)
+	if err != nil {
+		// Not a registry we can read; leave a copy before replacing it.
+		version = 0
+	}
+	if version > CurrentVersion {
+		return fmt.Errorf("%w: refusing to overwrite version %d registry at %s", ErrNewerRegistry, version, path)
+	}
+	if version == CurrentVersion {
+		return nil
+	}
+
+	backup := backupPath(path, version)
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	if err := atomicfile.WriteFile(backup, data, 0644); err != nil {
+		return fmt.Errorf("failed to back up registry: %w", err)
+	}
+	slog.Info("Backed up locations registry", "path", backup, "version", version)
+	return nil
+}
+
+// migrateV1ToV2 drops empty or duplicate address variants and moves NextID
+// past the highest location ID. Addresses are left to migrateV2ToV3, whose
+// normalization rules supersede the ones version 2 registries were written
+// with.
+func migrateV1ToV2(data []byte) ([]byte, error) {
+	return renormalize(data, 2, func(address string) string { return address })
+}
+
+// migrateV2ToV3 re-normalizes addresses now that unit designators are
+// stripped with a following letter ("apt e") and one-letter abbreviations
+// are only expanded where they stand for a street type or compass point.
+// It uses the built-in locales only, so the result is the same whichever
+// command migrates the registry and whatever abbreviation files the user
+// has.
+func migrateV2ToV3(data []byte) ([]byte, error) {
+	return renormalize(data, 3, func(address string) string {
+		return detectIn(builtinNormalizers, address).Normalize(address)
+	})
+}
+
+func renormalize(data []byte, version int, normalize func(string) string) ([]byte, error) {
+	var reg Registry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, err
+	}
+
+	maxID := 0
+	for i := range reg.Locations {
+		loc := &reg.Locations[i]
+		loc.CanonicalAddress = normalize(loc.CanonicalAddress)
+
+		variants := []string{}
+		for _, v := range loc.AddressVariants {
+			v = normalize(v)
+			if v != "" && v != loc.CanonicalAddress && !slices.Contains(variants, v) {
+				variants = append(variants, v)
+			}
+		}
+		loc.AddressVariants = variants
+
+		if n, err := strconv.Atoi(strings.TrimPrefix(loc.ID, "loc-")); err == nil {
+			maxID = max(maxID, n)
+		}
+	}
+	reg.NextID = max(reg.NextID, maxID+1)
+	reg.Version = version
+
+	return json.Marshal(reg)
+}
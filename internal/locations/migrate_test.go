@@ -0,0 +1,203 @@
+package locations
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+const legacyRegistry = `{
+  "locations": [
+    {
+      "id": "loc-4",
+      "canonicalAddress": "praça da sé 1",
+      "addressVariants": ["praca da se 1", "catedral da sé", ""],
+      "avgLat": -23.5503,
+      "avgLon": -46.6339,
+      "visitCount": 3
+    }
+  ],
+  "nextID": 2
+}`
+
+func writeRegistryFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "locations.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadMigratesLegacyRegistry(t *testing.T) {
+	path := writeRegistryFile(t, legacyRegistry)
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if registry.Version != CurrentVersion {
+		t.Errorf("expected version %d, got %d", CurrentVersion, registry.Version)
+	}
+
+	loc := registry.Locations[0]
+	if loc.CanonicalAddress != "praca da se 1" {
+		t.Errorf("expected re-normalized canonical address, got %q", loc.CanonicalAddress)
+	}
+	if !slices.Equal(loc.AddressVariants, []string{"catedral da se"}) {
+		t.Errorf("expected deduplicated variants, got %v", loc.AddressVariants)
+	}
+	if registry.NextID != 5 {
+		t.Errorf("expected NextID past the highest ID, got %d", registry.NextID)
+	}
+
+	if _, err := os.Stat(backupPath(path, 1)); !os.IsNotExist(err) {
+		t.Error("expected Load not to write a backup")
+	}
+}
+
+func TestLoadRefusesNewerRegistry(t *testing.T) {
+	path := writeRegistryFile(t, `{"version": 99, "locations": [], "nextID": 1}`)
+
+	if _, err := Load(path); !errors.Is(err, ErrNewerRegistry) {
+		t.Errorf("expected ErrNewerRegistry, got %v", err)
+	}
+}
+
+func TestSaveBacksUpOlderRegistry(t *testing.T) {
+	path := writeRegistryFile(t, legacyRegistry)
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if err := Save(registry, path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	backup, err := os.ReadFile(backupPath(path, 1))
+	if err != nil {
+		t.Fatalf("expected a backup of the version 1 registry: %v", err)
+	}
+	if string(backup) != legacyRegistry {
+		t.Error("expected the backup to hold the original file")
+	}
+
+	if err := Save(registry, path); err != nil {
+		t.Fatalf("second Save() failed: %v", err)
+	}
+	if backup, _ := os.ReadFile(backupPath(path, 1)); string(backup) != legacyRegistry {
+		t.Error("expected the backup to be kept on later saves")
+	}
+
+	saved, err := Load(path)
+	if err != nil || saved.Version != CurrentVersion {
+		t.Errorf("expected saved registry at version %d, got %v (%v)", CurrentVersion, saved, err)
+	}
+}
+
+func TestSaveRefusesToOverwriteNewerRegistry(t *testing.T) {
+	newer := `{"version": 99, "locations": [], "nextID": 1}`
+	path := writeRegistryFile(t, newer)
+
+	if err := Save(&Registry{NextID: 1}, path); !errors.Is(err, ErrNewerRegistry) {
+		t.Errorf("expected ErrNewerRegistry, got %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != newer {
+		t.Error("expected the newer registry to be left untouched")
+	}
+}
+
+func TestLoadMigratesRegistryInDetectedLocale(t *testing.T) {
+	path := writeRegistryFile(t, `{
+  "locations": [
+    {"id": "loc-1", "canonicalAddress": "100 main st springfield il usa", "addressVariants": ["100 main st springfield il 62701 usa"]},
+    {"id": "loc-2", "canonicalAddress": "c/ mayor 5 madrid espana", "addressVariants": []},
+    {"id": "loc-3", "canonicalAddress": "av paulista 1000 sao paulo", "addressVariants": []}
+  ],
+  "nextID": 4
+}`)
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	want := map[string]string{
+		"loc-1": "100 main street springfield il usa",
+		"loc-2": "calle mayor 5 madrid espana",
+		"loc-3": "avenida paulista 1000 sao paulo",
+	}
+	for _, loc := range registry.Locations {
+		if loc.CanonicalAddress != want[loc.ID] {
+			t.Errorf("%s: expected %q, got %q", loc.ID, want[loc.ID], loc.CanonicalAddress)
+		}
+	}
+
+	if got := NormalizeAddress("100 Main St, Springfield, IL, USA"); got != want["loc-1"] {
+		t.Errorf("expected fresh ingestion to match the migrated address, got %q", got)
+	}
+	if got := registry.Locations[0].AddressVariants; !slices.Equal(got, []string{"100 main street springfield il 62701 usa"}) {
+		t.Errorf("unexpected migrated variants: %v", got)
+	}
+}
+
+func TestLoadMigratesV2UnitLetters(t *testing.T) {
+	path := writeRegistryFile(t, `{
+  "version": 2,
+  "locations": [
+    {"id": "loc-1", "canonicalAddress": "12 oak road apt east austin tx usa", "addressVariants": ["12 oak road austin tx usa"]}
+  ],
+  "nextID": 2
+}`)
+
+	registry, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	loc := registry.Locations[0]
+	if loc.CanonicalAddress != "12 oak road austin tx usa" || len(loc.AddressVariants) != 0 {
+		t.Errorf("expected unit letter dropped and variant merged, got %q %v", loc.CanonicalAddress, loc.AddressVariants)
+	}
+}
+
+func TestLoadMigratesIgnoringUserAbbreviations(t *testing.T) {
+	const v2 = `{
+  "version": 2,
+  "locations": [
+    {"id": "loc-1", "canonicalAddress": "rua x 10 cj 5", "addressVariants": ["rua x 10"]}
+  ],
+  "nextID": 2
+}`
+
+	migrated := func() *Registry {
+		t.Helper()
+		registry, err := Load(writeRegistryFile(t, v2))
+		if err != nil {
+			t.Fatalf("Load() failed: %v", err)
+		}
+		return registry
+	}
+
+	without := migrated()
+
+	saved := maps.Clone(normalizers)
+	t.Cleanup(func() { normalizers = saved })
+	RegisterNormalizer(&LocaleNormalizer{Locale: "pt", Units: []string{"cj"}})
+
+	with := migrated()
+
+	if !reflect.DeepEqual(without.Locations, with.Locations) {
+		t.Errorf("expected the same migration with and without user abbreviations, got %+v and %+v", without.Locations, with.Locations)
+	}
+	if loc := with.Locations[0]; loc.CanonicalAddress != "rua x 10 cj 5" || !slices.Equal(loc.AddressVariants, []string{"rua x 10"}) {
+		t.Errorf("expected built-in rules to keep the user-defined unit, got %q %v", loc.CanonicalAddress, loc.AddressVariants)
+	}
+}
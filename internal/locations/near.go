@@ -0,0 +1,32 @@
+package locations
+
+import (
+	"cmp"
+	"slices"
+)
+
+// NearbyLocation is a location together with its distance in meters from a
+// queried point.
+type NearbyLocation struct {
+	*Location
+	Distance float64
+}
+
+// Near returns the locations within radius meters of (lat, lon), nearest
+// first. A radius of 0 or less returns every location.
+func (r *Registry) Near(lat, lon, radius float64) []NearbyLocation {
+	var result []NearbyLocation
+	for i := range r.Locations {
+		loc := &r.Locations[i]
+		distance := HaversineDistance(lat, lon, loc.AvgLat, loc.AvgLon)
+		if radius > 0 && distance > radius {
+			continue
+		}
+		result = append(result, NearbyLocation{Location: loc, Distance: distance})
+	}
+
+	slices.SortStableFunc(result, func(a, b NearbyLocation) int {
+		return cmp.Compare(a.Distance, b.Distance)
+	})
+	return result
+}
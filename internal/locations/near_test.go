@@ -0,0 +1,30 @@
+package locations
+
+import "testing"
+
+func nearRegistry() *Registry {
+	return &Registry{
+		Locations: []Location{
+			{ID: "loc-1", AvgLat: 41.4089, AvgLon: -75.6624},
+			{ID: "loc-2", AvgLat: 41.4120, AvgLon: -75.6580},
+			{ID: "loc-3", AvgLat: 41.4092, AvgLon: -75.6621},
+		},
+		NextID: 4,
+	}
+}
+
+func TestRegistryNear(t *testing.T) {
+	registry := nearRegistry()
+
+	nearby := registry.Near(41.4089, -75.6624, 100)
+	if len(nearby) != 2 || nearby[0].ID != "loc-1" || nearby[1].ID != "loc-3" {
+		t.Fatalf("expected loc-1 and loc-3 within 100 m, got %+v", nearby)
+	}
+	if nearby[0].Distance != 0 || nearby[1].Distance < 40 || nearby[1].Distance > 45 {
+		t.Errorf("unexpected distances: %v, %v", nearby[0].Distance, nearby[1].Distance)
+	}
+
+	if all := registry.Near(41.4089, -75.6624, 0); len(all) != 3 || all[2].ID != "loc-2" {
+		t.Errorf("expected all locations nearest first, got %+v", all)
+	}
+}
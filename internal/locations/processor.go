@@ -4,57 +4,199 @@ import (
 	"fmt"
 	"math"
 	"slices"
+	"strings"
 	"time"
+	"unicode"
 )
 
+// Processor keeps address and spatial indexes over its registry, so the
+// registry must not be modified by other code while the processor is in use.
 type Processor struct {
 	registry              *Registry
+	config                Config
+	normalizer            Normalizer
 	addressToVariantCount map[string]int
+	addresses             addressIndex
+	grid                  *spatialGrid
+	wide                  []int
+	visits                map[visitKey]string
+	stats                 IngestStats
+	seeds                 []radiusSeed
+}
+
+// radiusSeed is the custom radius of a location from another registry, kept
+// for the locations this processor creates at the same place.
+type radiusSeed struct {
+	lat, lon, radius float64
+}
+
+// IngestStats counts the visits a processor has recorded and the ones it
+// skipped because their trip was already part of the registry.
+type IngestStats struct {
+	Recorded int
+	Skipped  int
 }
 
 func NewProcessor(registry *Registry) *Processor {
+	return NewProcessorWithConfig(registry, DefaultConfig())
+}
+
+func NewProcessorWithConfig(registry *Registry, config Config) *Processor {
 	if registry == nil {
 		registry = &Registry{Locations: []Location{}, NextID: 1}
 	}
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultThreshold
+	}
+	if config.Algorithm == "" {
+		config.Algorithm = AlgorithmGreedy
+	}
+	if config.MinConfidence <= 0 {
+		config.MinConfidence = DefaultMinConfidence
+	}
+
+	normalizer, err := GetNormalizer(config.Locale)
+	if err != nil {
+		normalizer, _ = GetNormalizer(LocaleAuto)
+	}
 
 	p := &Processor{
 		registry:              registry,
+		config:                config,
+		normalizer:            normalizer,
 		addressToVariantCount: make(map[string]int),
 	}
 	p.rebuildVariantCounts()
+	p.rebuildIndex()
 	return p
 }
 
+// SeedRadii makes locations created at the place of a location in reg with
+// a custom radius get that radius too, so a registry rebuilt from scratch
+// keeps clustering those places as widely or narrowly as before.
+func (p *Processor) SeedRadii(reg *Registry) {
+	for _, loc := range reg.Locations {
+		if loc.Radius > 0 {
+			p.seeds = append(p.seeds, radiusSeed{lat: loc.AvgLat, lon: loc.AvgLon, radius: loc.Radius})
+		}
+	}
+}
+
+// seedAt returns the index of the closest seed within whose radius the
+// coordinates lie, or -1 when there is none.
+func (p *Processor) seedAt(lat, lon float64) int {
+	match, closest := -1, math.Inf(1)
+	for i, seed := range p.seeds {
+		d := HaversineDistance(lat, lon, seed.lat, seed.lon)
+		if d <= seed.radius && d < closest {
+			match, closest = i, d
+		}
+	}
+	return match
+}
+
+// seededRadius returns the radius of the seed at the coordinates, or 0.
+func (p *Processor) seededRadius(lat, lon float64) float64 {
+	if i := p.seedAt(lat, lon); i != -1 {
+		return p.seeds[i].radius
+	}
+	return 0
+}
+
 func (p *Processor) Registry() *Registry {
 	return p.registry
 }
 
-func (p *Processor) FindOrCreateLocation(address string, lat, lon float64) string {
-	normalizedAddr := NormalizeAddress(address)
+func (p *Processor) Stats() IngestStats {
+	return p.stats
+}
+
+// FindOrCreateLocation records a visit at the given address and coordinates
+// and returns the ID of the location it belongs to. A visit from a trip that
+// was already recorded returns the original location without counting again.
+// Visits without a time are stamped with the current time.
+func (p *Processor) FindOrCreateLocation(address string, lat, lon float64, visit Visit) string {
+	normalizedAddr := p.normalize(address)
 
 	if lat == 0 && lon == 0 {
 		return ""
 	}
 
-	now := time.Now()
+	if id, ok := p.recordedVisit(visit); ok {
+		return id
+	}
+
+	visit = p.prepareVisit(visit, lat, lon)
 
-	if i := p.findLocationByAddress(normalizedAddr); i != -1 {
-		loc := &p.registry.Locations[i]
-		p.updateLocation(loc, normalizedAddr, lat, lon, now)
-		return loc.ID
+	if i := p.findExistingLocation(normalizedAddr, lat, lon); i != -1 {
+		p.updateLocation(i, normalizedAddr, visit)
+		return p.registry.Locations[i].ID
 	}
 
-	for i := range p.registry.Locations {
-		loc := &p.registry.Locations[i]
+	return p.createNewLocation(normalizedAddr, visit)
+}
 
-		distance := HaversineDistance(lat, lon, loc.AvgLat, loc.AvgLon)
-		if distance <= meterThreshold {
-			p.updateLocation(loc, normalizedAddr, lat, lon, now)
-			return loc.ID
+// Locate returns the ID of the existing location the given address and
+// coordinates belong to, matched like FindOrCreateLocation, or "" when there
+// is none. It never changes the registry.
+func (p *Processor) Locate(address string, lat, lon float64, visit Visit) string {
+	if lat == 0 && lon == 0 {
+		return ""
+	}
+
+	if key, ok := visit.key(); ok {
+		if id, ok := p.visits[key]; ok {
+			return id
 		}
 	}
 
-	return p.createNewLocation(normalizedAddr, lat, lon, now)
+	if i := p.findExistingLocation(p.normalize(address), lat, lon); i != -1 {
+		return p.registry.Locations[i].ID
+	}
+	return ""
+}
+
+// findExistingLocation matches by exact normalized address, then by distance
+// within a location's radius, then by fuzzy address similarity nearby.
+func (p *Processor) findExistingLocation(address string, lat, lon float64) int {
+	if i := p.findLocationByAddress(address); i != -1 {
+		return i
+	}
+	if i := p.findLocationNear(lat, lon); i != -1 {
+		return i
+	}
+	return p.findLocationFuzzy(address, lat, lon)
+}
+
+// normalize normalizes an address, treating waypoints without any letters as
+// missing so they are never matched by address.
+func (p *Processor) normalize(address string) string {
+	normalized := p.normalizer.Normalize(address)
+	if strings.IndexFunc(normalized, unicode.IsLetter) == -1 {
+		return ""
+	}
+	return normalized
+}
+
+func (p *Processor) recordedVisit(visit Visit) (string, bool) {
+	key, ok := visit.key()
+	if !ok {
+		return "", false
+	}
+	id, ok := p.visits[key]
+	if ok {
+		p.stats.Skipped++
+	}
+	return id, ok
+}
+
+func (p *Processor) prepareVisit(visit Visit, lat, lon float64) Visit {
+	visit.Lat = lat
+	visit.Lon = lon
+	if visit.Time.IsZero() {
+		visit.Time = time.Now()
+	}
+	return visit
 }
 
 func (p *Processor) rebuildVariantCounts() {
@@ -67,40 +209,124 @@ func (p *Processor) rebuildVariantCounts() {
 	}
 }
 
-func (p *Processor) findLocationByAddress(normalizedAddress string) int {
+func (p *Processor) rebuildIndex() {
+	p.visits = make(map[visitKey]string)
+	p.addresses = make(addressIndex)
+	p.grid = newSpatialGrid(p.config.Threshold)
+	p.wide = nil
 	for i, loc := range p.registry.Locations {
-		if loc.CanonicalAddress == normalizedAddress {
-			return i
+		p.addresses.add(loc.CanonicalAddress, i)
+		for _, variant := range loc.AddressVariants {
+			p.addresses.add(variant, i)
 		}
-		if slices.Contains(loc.AddressVariants, normalizedAddress) {
-			return i
+		p.index(i)
+		for _, visit := range loc.Visits {
+			if key, ok := visit.key(); ok {
+				p.visits[key] = loc.ID
+			}
 		}
 	}
-	return -1
 }
 
-func (p *Processor) updateLocation(loc *Location, address string, lat, lon float64, now time.Time) {
+func (p *Processor) radius(loc Location) float64 {
+	if loc.Radius > 0 {
+		return loc.Radius
+	}
+	return p.config.Threshold
+}
+
+func (p *Processor) findLocationByAddress(normalizedAddress string) int {
+	return p.addresses.first(normalizedAddress)
+}
+
+// index adds the location at i to the spatial grid, or to the wide
+// locations when its radius is larger than the grid is searched for.
+func (p *Processor) index(i int) {
+	loc := p.registry.Locations[i]
+	if p.isWide(loc) {
+		p.wide = append(p.wide, i)
+		return
+	}
+	p.grid.insert(i, loc.AvgLat, loc.AvgLon)
+}
+
+func (p *Processor) isWide(loc Location) bool {
+	return loc.Radius > p.config.Threshold
+}
+
+// nearby returns the locations that may lie within reach(r) of (lat, lon),
+// where r is the radius of each location. The grid is searched as far as the
+// reach of the default radius; the few locations with a wider radius are
+// checked one by one, so a single wide location does not widen every search.
+func (p *Processor) nearby(lat, lon float64, reach func(radius float64) float64) []int {
+	result := p.grid.candidates(lat, lon, reach(p.config.Threshold))
+	for _, i := range p.wide {
+		loc := p.registry.Locations[i]
+		if HaversineDistance(lat, lon, loc.AvgLat, loc.AvgLon) <= reach(loc.Radius) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func (p *Processor) findLocationNear(lat, lon float64) int {
+	match := -1
+	best := math.Inf(1)
+	for _, i := range p.nearby(lat, lon, func(radius float64) float64 { return radius }) {
+		loc := p.registry.Locations[i]
+
+		distance := HaversineDistance(lat, lon, loc.AvgLat, loc.AvgLon)
+		if distance > p.radius(loc) {
+			continue
+		}
+		if distance < best || (distance == best && i < match) {
+			match = i
+			best = distance
+		}
+	}
+	return match
+}
+
+func (p *Processor) updateLocation(i int, address string, visit Visit) {
+	loc := &p.registry.Locations[i]
+	oldLat, oldLon := loc.AvgLat, loc.AvgLon
+	oldCanonical := loc.CanonicalAddress
+
 	p.decrementAddressCount(loc.CanonicalAddress)
 
 	loc.VisitCount++
-	loc.LastSeen = now
+	loc.FirstSeen = earliest(loc.FirstSeen, visit.Time)
+	loc.LastSeen = latest(loc.LastSeen, visit.Time)
+	p.recordVisit(loc, visit)
 
-	p.addAddressVariant(loc, address)
+	p.addAddressVariant(i, address)
 	p.incrementAddressCount(loc.CanonicalAddress)
 
-	p.updateAverageCoordinates(loc, lat, lon)
+	p.updateAverageCoordinates(loc, visit.Lat, visit.Lon)
 	p.updateCanonicalAddress(loc, address)
+
+	if loc.CanonicalAddress != oldCanonical {
+		p.addresses.add(loc.CanonicalAddress, i)
+		if !slices.Contains(loc.AddressVariants, oldCanonical) {
+			p.addresses.remove(oldCanonical, i)
+		}
+	}
+	if !p.isWide(*loc) {
+		p.grid.move(i, oldLat, oldLon, loc.AvgLat, loc.AvgLon)
+	}
 }
 
-func (p *Processor) addAddressVariant(loc *Location, address string) {
+func (p *Processor) addAddressVariant(i int, address string) {
+	loc := &p.registry.Locations[i]
 	p.incrementAddressCount(address)
 
-	if slices.Contains(loc.AddressVariants, address) {
+	if address == "" || slices.Contains(loc.AddressVariants, address) {
 		return
 	}
 
-	if address != loc.CanonicalAddress {
+	if loc.CanonicalAddress != "" && address != loc.CanonicalAddress {
 		loc.AddressVariants = append(loc.AddressVariants, address)
+		p.addresses.add(address, i)
 	}
 }
 
@@ -115,7 +341,10 @@ func (p *Processor) updateAverageCoordinates(loc *Location, lat, lon float64) {
 }
 
 func (p *Processor) updateCanonicalAddress(loc *Location, address string) {
-	if p.addressToVariantCount[address] > p.addressToVariantCount[loc.CanonicalAddress] {
+	if address == "" {
+		return
+	}
+	if loc.CanonicalAddress == "" || p.addressToVariantCount[address] > p.addressToVariantCount[loc.CanonicalAddress] {
 		loc.CanonicalAddress = address
 	}
 }
@@ -128,7 +357,20 @@ func (p *Processor) decrementAddressCount(address string) {
 	p.addressToVariantCount[address]--
 }
 
-func (p *Processor) createNewLocation(address string, lat, lon float64, now time.Time) string {
+func (p *Processor) recordVisit(loc *Location, visit Visit) {
+	p.stats.Recorded++
+	if key, ok := visit.key(); ok {
+		p.visits[key] = loc.ID
+	}
+
+	n := len(loc.Visits)
+	loc.Visits = append(loc.Visits, visit)
+	if n > 0 && visit.Time.Before(loc.Visits[n-1].Time) {
+		sortVisits(loc.Visits)
+	}
+}
+
+func (p *Processor) createNewLocation(address string, visit Visit) string {
 	locID := fmt.Sprintf("loc-%d", p.registry.NextID)
 	p.registry.NextID++
 
@@ -136,16 +378,22 @@ func (p *Processor) createNewLocation(address string, lat, lon float64, now time
 		ID:               locID,
 		CanonicalAddress: address,
 		AddressVariants:  []string{},
-		AvgLat:           lat,
-		AvgLon:           lon,
+		AvgLat:           visit.Lat,
+		AvgLon:           visit.Lon,
 		VisitCount:       1,
-		FirstSeen:        now,
-		LastSeen:         now,
+		FirstSeen:        visit.Time,
+		LastSeen:         visit.Time,
+		Radius:           p.seededRadius(visit.Lat, visit.Lon),
 	}
 
 	p.registry.Locations = append(p.registry.Locations, newLoc)
 	p.addressToVariantCount[address]++
 
+	i := len(p.registry.Locations) - 1
+	p.recordVisit(&p.registry.Locations[i], visit)
+	p.addresses.add(address, i)
+	p.index(i)
+
 	return locID
 }
 
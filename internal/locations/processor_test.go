@@ -2,6 +2,9 @@ package locations
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
 	"os"
 	"testing"
 	"time"
@@ -69,7 +72,7 @@ func TestFindOrCreateLocation(t *testing.T) {
 	processor := NewProcessor(registry)
 
 	t.Run("create new location", func(t *testing.T) {
-		locID := processor.FindOrCreateLocation("123 Test Street", 41.4089, -75.6624)
+		locID := processor.FindOrCreateLocation("123 Test Street", 41.4089, -75.6624, Visit{})
 		if locID != "loc-1" {
 			t.Errorf("expected loc-1, got %s", locID)
 		}
@@ -85,7 +88,7 @@ func TestFindOrCreateLocation(t *testing.T) {
 	})
 
 	t.Run("find existing location by address", func(t *testing.T) {
-		locID := processor.FindOrCreateLocation("123 test street", 41.4089, -75.6624)
+		locID := processor.FindOrCreateLocation("123 test street", 41.4089, -75.6624, Visit{})
 		if locID != "loc-1" {
 			t.Errorf("expected loc-1, got %s", locID)
 		}
@@ -101,7 +104,7 @@ func TestFindOrCreateLocation(t *testing.T) {
 		registry.Locations[0].AvgLat = 41.4089
 		registry.Locations[0].AvgLon = -75.6624
 
-		locID := processor.FindOrCreateLocation("124 Test Street", 41.408905, -75.662405)
+		locID := processor.FindOrCreateLocation("124 Test Street", 41.408905, -75.662405, Visit{})
 		if locID != "loc-1" {
 			t.Errorf("expected loc-1 (clustered), got %s", locID)
 		}
@@ -118,7 +121,7 @@ func TestFindOrCreateLocation(t *testing.T) {
 
 	t.Run("create separate location outside threshold", func(t *testing.T) {
 		registry.NextID = 2
-		locID := processor.FindOrCreateLocation("456 Far Away Street", 41.4500, -75.6200)
+		locID := processor.FindOrCreateLocation("456 Far Away Street", 41.4500, -75.6200, Visit{})
 		if locID != "loc-2" {
 			t.Errorf("expected loc-2, got %s", locID)
 		}
@@ -129,7 +132,7 @@ func TestFindOrCreateLocation(t *testing.T) {
 	})
 
 	t.Run("ignore zero coordinates", func(t *testing.T) {
-		locID := processor.FindOrCreateLocation("Invalid Location", 0, 0)
+		locID := processor.FindOrCreateLocation("Invalid Location", 0, 0, Visit{})
 		if locID != "" {
 			t.Errorf("expected empty string for zero coordinates, got %s", locID)
 		}
@@ -150,7 +153,7 @@ func TestFindOrCreateLocationWithRegistry(t *testing.T) {
 	processor := NewProcessor(&registry)
 
 	t.Run("find exact address match", func(t *testing.T) {
-		locID := processor.FindOrCreateLocation("1725 slough avenue - scranton business park - scranton - pa 18505", 41.4089, -75.6624)
+		locID := processor.FindOrCreateLocation("1725 slough avenue - scranton business park - scranton - pa 18505", 41.4089, -75.6624, Visit{})
 		if locID != "loc-1" {
 			t.Errorf("expected loc-1, got %s", locID)
 		}
@@ -162,7 +165,7 @@ func TestFindOrCreateLocationWithRegistry(t *testing.T) {
 	})
 
 	t.Run("find address variant match", func(t *testing.T) {
-		locID := processor.FindOrCreateLocation("michael scott residence - 123 kellum court - scranton - pa 18508", 41.4120, -75.6580)
+		locID := processor.FindOrCreateLocation("michael scott residence - 123 kellum court - scranton - pa 18508", 41.4120, -75.6580, Visit{})
 		if locID != "loc-2" {
 			t.Errorf("expected loc-2, got %s", locID)
 		}
@@ -175,7 +178,7 @@ func TestFindOrCreateLocationWithRegistry(t *testing.T) {
 
 	t.Run("cluster new location near existing", func(t *testing.T) {
 		initialCount := registry.Locations[0].VisitCount
-		locID := processor.FindOrCreateLocation("1725 Slough Avenue", 41.408901, -75.662401)
+		locID := processor.FindOrCreateLocation("1725 Slough Avenue", 41.408901, -75.662401, Visit{})
 		if locID != "loc-1" {
 			t.Errorf("expected loc-1 (clustered), got %s", locID)
 		}
@@ -188,7 +191,7 @@ func TestFindOrCreateLocationWithRegistry(t *testing.T) {
 
 	t.Run("create new location far from existing", func(t *testing.T) {
 		registry.NextID = 10
-		locID := processor.FindOrCreateLocation("New York Times Square", 40.7580, -73.9855)
+		locID := processor.FindOrCreateLocation("New York Times Square", 40.7580, -73.9855, Visit{})
 		if locID != "loc-10" {
 			t.Errorf("expected loc-10, got %s", locID)
 		}
@@ -234,7 +237,7 @@ func TestUpdateLocation(t *testing.T) {
 	initialLat := registry.Locations[0].AvgLat
 	initialLon := registry.Locations[0].AvgLon
 
-	processor.FindOrCreateLocation("123 TEST STREET", 41.4090, -75.6625)
+	processor.FindOrCreateLocation("123 TEST STREET", 41.4090, -75.6625, Visit{})
 
 	loc := registry.Locations[0]
 
@@ -250,3 +253,264 @@ func TestUpdateLocation(t *testing.T) {
 		t.Error("expected average longitude to change")
 	}
 }
+
+func TestFindOrCreateLocationCanonicalChange(t *testing.T) {
+	registry := &Registry{Locations: []Location{}, NextID: 1}
+	processor := NewProcessor(registry)
+
+	processor.FindOrCreateLocation("Rua A, 1", -23.5614, -46.6559, Visit{})
+	processor.FindOrCreateLocation("Rua B, 1", -23.56141, -46.65591, Visit{})
+	processor.FindOrCreateLocation("Rua B, 1", -23.56141, -46.65591, Visit{})
+
+	loc := registry.Locations[0]
+	if loc.CanonicalAddress != "rua b 1" {
+		t.Fatalf("expected canonical address to change to the most common variant, got %q", loc.CanonicalAddress)
+	}
+
+	if processor.findLocationByAddress("rua b 1") != 0 {
+		t.Error("expected new canonical address to be indexed")
+	}
+
+	if locID := processor.FindOrCreateLocation("Rua B, 1", -23.5700, -46.6700, Visit{}); locID != "loc-1" {
+		t.Errorf("expected address match to win over distance, got %s", locID)
+	}
+}
+
+func benchmarkRegistry(n int) *Registry {
+	rng := rand.New(rand.NewSource(1))
+
+	registry := &Registry{Locations: make([]Location, 0, n), NextID: n + 1}
+	for i := 0; i < n; i++ {
+		registry.Locations = append(registry.Locations, Location{
+			ID:               fmt.Sprintf("loc-%d", i+1),
+			CanonicalAddress: fmt.Sprintf("rua %d", i),
+			AddressVariants:  []string{fmt.Sprintf("avenida %d", i)},
+			AvgLat:           -23.55 + (rng.Float64()-0.5)*0.5,
+			AvgLon:           -46.63 + (rng.Float64()-0.5)*0.5,
+			VisitCount:       1,
+		})
+	}
+	return registry
+}
+
+func BenchmarkFindOrCreateLocation(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("locations=%d", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(2))
+			processor := NewProcessor(benchmarkRegistry(n))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				lat := -23.55 + (rng.Float64()-0.5)*0.5
+				lon := -46.63 + (rng.Float64()-0.5)*0.5
+				processor.FindOrCreateLocation(fmt.Sprintf("rua nova %d", i), lat, lon, Visit{})
+			}
+		})
+	}
+}
+
+func BenchmarkLocateWithRadius(b *testing.B) {
+	for _, radius := range []float64{0, 300, 1000, 5000} {
+		b.Run(fmt.Sprintf("radius=%.0f", radius), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(2))
+			registry := benchmarkRegistry(2000)
+			registry.Locations[0].Radius = radius
+			processor := NewProcessor(registry)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				lat := -23.55 + (rng.Float64()-0.5)*0.5
+				lon := -46.63 + (rng.Float64()-0.5)*0.5
+				processor.Locate(fmt.Sprintf("rua nova %d", i), lat, lon, Visit{})
+			}
+		})
+	}
+}
+
+func BenchmarkFindOrCreateLocationByAddress(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		b.Run(fmt.Sprintf("locations=%d", n), func(b *testing.B) {
+			processor := NewProcessor(benchmarkRegistry(n))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				j := i % n
+				loc := processor.Registry().Locations[j]
+				processor.FindOrCreateLocation(fmt.Sprintf("avenida %d", j), loc.AvgLat, loc.AvgLon, Visit{})
+			}
+		})
+	}
+}
+
+func TestFindOrCreateLocationNearest(t *testing.T) {
+	registry := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "entrance a", AddressVariants: []string{}, AvgLat: 41.40890, AvgLon: -75.6624, VisitCount: 1},
+			{ID: "loc-2", CanonicalAddress: "entrance b", AddressVariants: []string{}, AvgLat: 41.40910, AvgLon: -75.6624, VisitCount: 1},
+		},
+		NextID: 3,
+	}
+
+	processor := NewProcessor(registry)
+
+	locID := processor.FindOrCreateLocation("lobby", 41.40905, -75.6624, Visit{})
+	if locID != "loc-2" {
+		t.Errorf("expected nearest location loc-2, got %s", locID)
+	}
+}
+
+func TestFindOrCreateLocationRadius(t *testing.T) {
+	registry := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "airport", AddressVariants: []string{}, AvgLat: -23.4356, AvgLon: -46.4731, VisitCount: 1, Radius: 500},
+		},
+		NextID: 2,
+	}
+
+	processor := NewProcessor(registry)
+
+	if locID := processor.FindOrCreateLocation("terminal 3", -23.4386, -46.4731, Visit{}); locID != "loc-1" {
+		t.Errorf("expected point within location radius to match loc-1, got %s", locID)
+	}
+
+	if locID := processor.FindOrCreateLocation("hotel", -23.4456, -46.4731, Visit{}); locID != "loc-2" {
+		t.Errorf("expected point outside location radius to create loc-2, got %s", locID)
+	}
+}
+
+func TestSeedRadii(t *testing.T) {
+	current := &Registry{
+		Locations: []Location{
+			{ID: "loc-4", CanonicalAddress: "airport", AvgLat: -23.4356, AvgLon: -46.4731, VisitCount: 9, Radius: 500},
+		},
+		NextID: 5,
+	}
+	points := []Point{
+		{Address: "terminal 1", Lat: -23.4366, Lon: -46.4731},
+		{Address: "terminal 3", Lat: -23.4386, Lon: -46.4731},
+		{Address: "hotel", Lat: -23.4456, Lon: -46.4731},
+	}
+
+	for _, algorithm := range []Algorithm{AlgorithmGreedy, AlgorithmDBSCAN} {
+		processor := NewProcessorWithConfig(nil, Config{Algorithm: algorithm})
+		processor.SeedRadii(current)
+
+		ids := processor.AssignBatch(points)
+		if ids[0] != "loc-1" || ids[1] != "loc-1" || ids[2] != "loc-2" {
+			t.Errorf("%s: expected terminals clustered within the seeded radius, got %v", algorithm, ids)
+		}
+
+		locs := processor.Registry().Locations
+		if len(locs) != 2 || locs[0].Radius != 500 || locs[1].Radius != 0 {
+			t.Errorf("%s: expected only the airport location to get the seeded radius, got %+v", algorithm, locs)
+		}
+	}
+}
+
+func TestFindOrCreateLocationWideRadius(t *testing.T) {
+	for _, lat := range []float64{0, 64.15, 78.22} {
+		registry := &Registry{
+			Locations: []Location{
+				{ID: "loc-1", CanonicalAddress: "airport", AddressVariants: []string{}, AvgLat: lat, AvgLon: 0, VisitCount: 1, Radius: 2000},
+				{ID: "loc-2", CanonicalAddress: "hotel", AddressVariants: []string{}, AvgLat: lat + 0.1, AvgLon: 0, VisitCount: 1},
+			},
+			NextID: 3,
+		}
+		processor := NewProcessor(registry)
+
+		// 1890 m east and north of the airport.
+		east := 1890 / (metersPerDegree * math.Cos(toRadians(lat)))
+		if locID := processor.Locate("cargo terminal", lat, east, Visit{}); locID != "loc-1" {
+			t.Errorf("lat %v: expected point 1890 m east within the 2000 m radius to match loc-1, got %s", lat, locID)
+		}
+		if locID := processor.FindOrCreateLocation("gate 7", lat+1890/metersPerDegree, 0, Visit{}); locID != "loc-1" {
+			t.Errorf("lat %v: expected point 1890 m north within the 2000 m radius to match loc-1, got %s", lat, locID)
+		}
+		if locID := processor.FindOrCreateLocation("hotel", lat+0.1, 0.0001, Visit{}); locID != "loc-2" {
+			t.Errorf("lat %v: expected hotel to match loc-2, got %s", lat, locID)
+		}
+		if len(registry.Locations) != 2 {
+			t.Errorf("lat %v: expected no new locations, got %d", lat, len(registry.Locations))
+		}
+	}
+}
+
+func TestFindOrCreateLocationThreshold(t *testing.T) {
+	registry := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "mall", AddressVariants: []string{}, AvgLat: -23.5614, AvgLon: -46.6559, VisitCount: 1},
+		},
+		NextID: 2,
+	}
+
+	processor := NewProcessorWithConfig(registry, Config{Threshold: 100})
+
+	if locID := processor.FindOrCreateLocation("mall parking", -23.5620, -46.6559, Visit{}); locID != "loc-1" {
+		t.Errorf("expected point within configured threshold to match loc-1, got %s", locID)
+	}
+}
+
+func TestFindOrCreateLocationVisitTimes(t *testing.T) {
+	processor := NewProcessor(nil)
+
+	march := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	january := time.Date(2024, 1, 15, 18, 30, 0, 0, time.UTC)
+
+	processor.FindOrCreateLocation("1725 Slough Avenue", 41.4089, -75.6624, Visit{TripUUID: "trip-2", Role: VisitPickup, Time: march})
+	locID := processor.FindOrCreateLocation("1725 Slough Avenue", 41.4089, -75.6624, Visit{TripUUID: "trip-1", Role: VisitDropoff, Time: january})
+
+	loc, _ := processor.Registry().Find(locID)
+	if !loc.FirstSeen.Equal(january) || !loc.LastSeen.Equal(march) {
+		t.Errorf("expected first/last seen from trip times, got %v and %v", loc.FirstSeen, loc.LastSeen)
+	}
+
+	if len(loc.Visits) != 2 || loc.Visits[0].TripUUID != "trip-1" || loc.Visits[1].Role != VisitPickup {
+		t.Errorf("expected visit log ordered by time, got %+v", loc.Visits)
+	}
+}
+
+func TestFindOrCreateLocationSkipsRecordedVisits(t *testing.T) {
+	registry := &Registry{NextID: 1}
+	visit := Visit{TripUUID: "trip-1", Role: VisitPickup, Time: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+
+	first := NewProcessor(registry).FindOrCreateLocation("1725 Slough Avenue", 41.4089, -75.6624, visit)
+
+	// A fresh processor over the same registry must see the recorded visit.
+	processor := NewProcessor(registry)
+	if locID := processor.FindOrCreateLocation("1725 Slough Avenue", 41.4089, -75.6624, visit); locID != first {
+		t.Errorf("expected recorded visit to resolve to %s, got %s", first, locID)
+	}
+
+	dropoff := visit
+	dropoff.Role = VisitDropoff
+	processor.FindOrCreateLocation("1725 Slough Avenue", 41.4089, -75.6624, dropoff)
+
+	loc, _ := registry.Find(first)
+	if loc.VisitCount != 2 || len(loc.Visits) != 2 {
+		t.Errorf("expected 2 visits after re-ingesting a trip, got count %d and log %d", loc.VisitCount, len(loc.Visits))
+	}
+
+	if stats := processor.Stats(); stats != (IngestStats{Recorded: 1, Skipped: 1}) {
+		t.Errorf("unexpected ingest stats: %+v", stats)
+	}
+}
+
+func TestFindOrCreateLocationWithoutAddress(t *testing.T) {
+	processor := NewProcessor(nil)
+
+	first := processor.FindOrCreateLocation("", 41.4089, -75.6624, Visit{})
+	second := processor.FindOrCreateLocation(" , 123", -23.5614, -46.6559, Visit{})
+	if first == second {
+		t.Errorf("expected distant points without an address to stay apart, got %s twice", first)
+	}
+
+	processor.FindOrCreateLocation("1725 Slough Avenue", 41.40891, -75.66241, Visit{})
+	loc, _ := processor.Registry().Find(first)
+	if loc.CanonicalAddress != "1725 slough avenue" || len(loc.AddressVariants) != 0 {
+		t.Errorf("expected the first real address to become canonical, got %q and %v", loc.CanonicalAddress, loc.AddressVariants)
+	}
+
+	if locID := processor.FindOrCreateLocation("1725 Slough Avenue", 41.4200, -75.6624, Visit{}); locID != first {
+		t.Errorf("expected the new canonical address to be matched, got %s", locID)
+	}
+}
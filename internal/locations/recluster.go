@@ -0,0 +1,160 @@
+package locations
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+type ChangeKind string
+
+const (
+	ChangeKept    ChangeKind = "kept"
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+)
+
+type Change struct {
+	Kind      ChangeKind
+	ID        string
+	Label     string
+	Address   string
+	OldVisits int
+	NewVisits int
+	Moved     float64
+}
+
+type ReclusterResult struct {
+	Registry *Registry
+	IDs      map[string]string
+	Changes  []Change
+}
+
+// Reconcile maps the locations of a freshly clustered registry onto the
+// current one. A fresh location keeps the ID, label and radius of a current
+// location when the two are each other's closest match, either sharing an
+// address or lying within the matching radius. Other fresh locations get new
+// IDs that never reuse one from the current registry.
+func Reconcile(current, fresh *Registry, threshold float64) *ReclusterResult {
+	type pair struct {
+		cur, fr  int
+		distance float64
+		address  bool
+	}
+
+	var pairs []pair
+	for i, cur := range current.Locations {
+		radius := threshold
+		if cur.Radius > 0 {
+			radius = cur.Radius
+		}
+
+		for j, fr := range fresh.Locations {
+			distance := HaversineDistance(cur.AvgLat, cur.AvgLon, fr.AvgLat, fr.AvgLon)
+			address := sharesAddress(cur, fr)
+			if address || distance <= radius {
+				pairs = append(pairs, pair{cur: i, fr: j, distance: distance, address: address})
+			}
+		}
+	}
+
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		if a.address != b.address {
+			if a.address {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.distance, b.distance)
+	})
+
+	curMatch := make(map[int]int)
+	frMatch := make(map[int]int)
+	for _, p := range pairs {
+		if _, ok := curMatch[p.cur]; ok {
+			continue
+		}
+		if _, ok := frMatch[p.fr]; ok {
+			continue
+		}
+		curMatch[p.cur] = p.fr
+		frMatch[p.fr] = p.cur
+	}
+
+	result := &ReclusterResult{
+		Registry: &Registry{
+			Locations: make([]Location, 0, len(fresh.Locations)),
+			NextID:    max(current.NextID, 1),
+			Aliases:   make(map[string]string),
+		},
+		IDs: make(map[string]string),
+	}
+
+	for j, fr := range fresh.Locations {
+		loc := fr
+
+		if i, ok := frMatch[j]; ok {
+			cur := current.Locations[i]
+			loc.ID = cur.ID
+			loc.Label = cur.Label
+			loc.Radius = cur.Radius
+
+			result.Changes = append(result.Changes, Change{
+				Kind:      ChangeKept,
+				ID:        loc.ID,
+				Label:     loc.Label,
+				Address:   loc.CanonicalAddress,
+				OldVisits: cur.VisitCount,
+				NewVisits: loc.VisitCount,
+				Moved:     HaversineDistance(cur.AvgLat, cur.AvgLon, loc.AvgLat, loc.AvgLon),
+			})
+		} else {
+			loc.ID = fmt.Sprintf("loc-%d", result.Registry.NextID)
+			result.Registry.NextID++
+
+			result.Changes = append(result.Changes, Change{
+				Kind:      ChangeAdded,
+				ID:        loc.ID,
+				Address:   loc.CanonicalAddress,
+				NewVisits: loc.VisitCount,
+			})
+		}
+
+		result.IDs[fr.ID] = loc.ID
+		result.Registry.Locations = append(result.Registry.Locations, loc)
+	}
+
+	for i, cur := range current.Locations {
+		if _, ok := curMatch[i]; ok {
+			continue
+		}
+		result.Changes = append(result.Changes, Change{
+			Kind:      ChangeRemoved,
+			ID:        cur.ID,
+			Label:     cur.Label,
+			Address:   cur.CanonicalAddress,
+			OldVisits: cur.VisitCount,
+		})
+	}
+
+	for alias, target := range current.Aliases {
+		if _, err := result.Registry.Find(target); err == nil {
+			result.Registry.Aliases[alias] = target
+		}
+	}
+	if len(result.Registry.Aliases) == 0 {
+		result.Registry.Aliases = nil
+	}
+
+	return result
+}
+
+func sharesAddress(a, b Location) bool {
+	addresses := append([]string{a.CanonicalAddress}, a.AddressVariants...)
+	for _, addr := range append([]string{b.CanonicalAddress}, b.AddressVariants...) {
+		if addr != "" && slices.Contains(addresses, addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package locations
+
+import (
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	current := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", Label: "office", CanonicalAddress: "1725 slough avenue", AvgLat: 41.4089, AvgLon: -75.6624, VisitCount: 10},
+			{ID: "loc-4", CanonicalAddress: "123 kellum court", AvgLat: 41.4120, AvgLon: -75.6580, VisitCount: 5, Radius: 80},
+			{ID: "loc-6", CanonicalAddress: "5561 moseley road", AvgLat: 41.4500, AvgLon: -75.6200, VisitCount: 2},
+		},
+		NextID:  7,
+		Aliases: map[string]string{"loc-2": "loc-1", "loc-5": "loc-6"},
+	}
+
+	fresh := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "123 kellum court - scranton", AddressVariants: []string{}, AvgLat: 41.4124, AvgLon: -75.6580, VisitCount: 6},
+			{ID: "loc-2", CanonicalAddress: "dunder mifflin", AddressVariants: []string{"1725 slough avenue"}, AvgLat: 41.4090, AvgLon: -75.6624, VisitCount: 12},
+			{ID: "loc-3", CanonicalAddress: "new york times square", AddressVariants: []string{}, AvgLat: 40.7580, AvgLon: -73.9855, VisitCount: 1},
+		},
+		NextID: 4,
+	}
+
+	result := Reconcile(current, fresh, DefaultThreshold)
+
+	wantIDs := map[string]string{"loc-1": "loc-4", "loc-2": "loc-1", "loc-3": "loc-7"}
+	for freshID, want := range wantIDs {
+		if got := result.IDs[freshID]; got != want {
+			t.Errorf("fresh %s: expected %s, got %s", freshID, want, got)
+		}
+	}
+
+	office, err := result.Registry.Find("office")
+	if err != nil {
+		t.Fatalf("expected label to be preserved: %v", err)
+	}
+	if office.ID != "loc-1" || office.VisitCount != 12 {
+		t.Errorf("expected office to be loc-1 with 12 visits, got %s with %d", office.ID, office.VisitCount)
+	}
+
+	kellum, _ := result.Registry.Find("loc-4")
+	if kellum.Radius != 80 {
+		t.Errorf("expected radius to be preserved, got %v", kellum.Radius)
+	}
+
+	if result.Registry.NextID != 8 {
+		t.Errorf("expected NextID 8, got %d", result.Registry.NextID)
+	}
+
+	if _, ok := result.Registry.Aliases["loc-2"]; !ok {
+		t.Error("expected alias to a kept location to be preserved")
+	}
+	if _, ok := result.Registry.Aliases["loc-5"]; ok {
+		t.Error("expected alias to a removed location to be dropped")
+	}
+
+	counts := make(map[ChangeKind]int)
+	for _, c := range result.Changes {
+		counts[c.Kind]++
+		if c.Kind == ChangeRemoved && c.ID != "loc-6" {
+			t.Errorf("expected loc-6 to be removed, got %s", c.ID)
+		}
+	}
+	if counts[ChangeKept] != 2 || counts[ChangeAdded] != 1 || counts[ChangeRemoved] != 1 {
+		t.Errorf("unexpected change counts: %v", counts)
+	}
+}
+
+func TestReconcileMutualMatch(t *testing.T) {
+	current := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "gate a", AvgLat: 41.40890, AvgLon: -75.6624},
+		},
+		NextID: 2,
+	}
+
+	fresh := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "gate b", AvgLat: 41.40905, AvgLon: -75.6624},
+			{ID: "loc-2", CanonicalAddress: "gate c", AvgLat: 41.40895, AvgLon: -75.6624},
+		},
+		NextID: 3,
+	}
+
+	result := Reconcile(current, fresh, DefaultThreshold)
+
+	if result.IDs["loc-2"] != "loc-1" {
+		t.Errorf("expected the closest fresh location to keep loc-1, got %s", result.IDs["loc-2"])
+	}
+	if result.IDs["loc-1"] != "loc-2" {
+		t.Errorf("expected the other fresh location to get a new ID, got %s", result.IDs["loc-1"])
+	}
+}
@@ -1,30 +1,165 @@
 package locations
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"uber-extractor/internal/atomicfile"
 	"uber-extractor/internal/auth"
 )
 
+var (
+	ErrLocationNotFound = errors.New("location not found")
+	ErrInvalidLabel     = errors.New("invalid label")
+	ErrDuplicateLabel   = errors.New("label already in use")
+	ErrInvalidRadius    = errors.New("invalid radius")
+)
+
+var locationIDRegex = regexp.MustCompile(`^loc-\d+$`)
+
 type Registry struct {
-	Locations []Location `json:"locations"`
-	NextID    int        `json:"nextID"`
+	Version   int               `json:"version"`
+	Locations []Location        `json:"locations"`
+	NextID    int               `json:"nextID"`
+	Aliases   map[string]string `json:"aliases,omitempty"`
 }
 
 type Location struct {
 	ID               string    `json:"id"`
+	Label            string    `json:"label,omitempty"`
 	CanonicalAddress string    `json:"canonicalAddress"`
 	AddressVariants  []string  `json:"addressVariants"`
 	AvgLat           float64   `json:"avgLat"`
 	AvgLon           float64   `json:"avgLon"`
+	Radius           float64   `json:"radius,omitempty"`
+	Neighbourhood    string    `json:"neighbourhood,omitempty"`
+	City             string    `json:"city,omitempty"`
+	Country          string    `json:"country,omitempty"`
 	VisitCount       int       `json:"visitCount"`
 	FirstSeen        time.Time `json:"firstSeen"`
 	LastSeen         time.Time `json:"lastSeen"`
+	Visits           []Visit   `json:"visits,omitempty"`
+}
+
+func (l *Location) Name() string {
+	if l.Label != "" {
+		return l.Label
+	}
+	return l.ID
+}
+
+// DisplayAddress returns the canonical address, or the neighbourhood, city
+// and country when the trips had no usable address.
+func (l *Location) DisplayAddress() string {
+	if l.CanonicalAddress != "" {
+		return l.CanonicalAddress
+	}
+	var parts []string
+	for _, s := range []string{l.Neighbourhood, l.City, l.Country} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MapURL links to the location's average coordinates on Google Maps.
+func (l *Location) MapURL() string {
+	return fmt.Sprintf("https://www.google.com/maps/search/?api=1&query=%.6f,%.6f", l.AvgLat, l.AvgLon)
+}
+
+func (r *Registry) Find(ref string) (*Location, error) {
+	ref = strings.TrimSpace(ref)
+	for i := range r.Locations {
+		if r.Locations[i].ID == ref {
+			return &r.Locations[i], nil
+		}
+	}
+	for i := range r.Locations {
+		if r.Locations[i].Label != "" && strings.EqualFold(r.Locations[i].Label, ref) {
+			return &r.Locations[i], nil
+		}
+	}
+	if target, ok := r.Aliases[ref]; ok && target != ref {
+		return r.Find(target)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLocationNotFound, ref)
+}
+
+func (r *Registry) Label(id string) string {
+	for _, loc := range r.Locations {
+		if loc.ID == id {
+			return loc.Label
+		}
+	}
+	return ""
+}
+
+func (r *Registry) SetLabel(ref, label string) error {
+	loc, err := r.Find(ref)
+	if err != nil {
+		return err
+	}
+
+	label = strings.TrimSpace(label)
+	if label == "" {
+		loc.Label = ""
+		return nil
+	}
+
+	if locationIDRegex.MatchString(label) {
+		return fmt.Errorf("%w: %q looks like a location ID", ErrInvalidLabel, label)
+	}
+
+	for _, other := range r.Locations {
+		if other.ID != loc.ID && strings.EqualFold(other.Label, label) {
+			return fmt.Errorf("%w: %q is assigned to %s", ErrDuplicateLabel, label, other.ID)
+		}
+	}
+
+	loc.Label = label
+	return nil
+}
+
+func (r *Registry) SetRadius(ref string, meters float64) error {
+	loc, err := r.Find(ref)
+	if err != nil {
+		return err
+	}
+
+	if meters < 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidRadius, meters)
+	}
+
+	loc.Radius = meters
+	return nil
+}
+
+func (r *Registry) Delete(ref string) (Location, error) {
+	loc, err := r.Find(ref)
+	if err != nil {
+		return Location{}, err
+	}
+
+	deleted := *loc
+	r.Locations = slices.DeleteFunc(r.Locations, func(l Location) bool {
+		return l.ID == deleted.ID
+	})
+	for alias, target := range r.Aliases {
+		if target == deleted.ID {
+			delete(r.Aliases, alias)
+		}
+	}
+	return deleted, nil
 }
 
 func getDefaultPath() string {
@@ -44,12 +179,23 @@ func Load(path ...string) (*Registry, error) {
 	data, err := os.ReadFile(p)
 	if os.IsNotExist(err) {
 		slog.Info("Locations registry not found, creating new", "path", p)
-		return &Registry{Locations: []Location{}, NextID: 1}, nil
+		return &Registry{Version: CurrentVersion, Locations: []Location{}, NextID: 1}, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load registry: %w", err)
 	}
 
+	data, version, err := migrate(data)
+	if err != nil {
+		if errors.Is(err, ErrNewerRegistry) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	if version < CurrentVersion {
+		slog.Info("Migrated locations registry", "path", p, "from", version, "to", CurrentVersion)
+	}
+
 	var reg Registry
 	if err := json.Unmarshal(data, &reg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal: %w", err)
@@ -70,15 +216,41 @@ func Save(reg *Registry, path ...string) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
+	existing, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read existing registry: %w", err)
+	}
+	if existing != nil {
+		if err := checkExisting(p, existing); err != nil {
+			return err
+		}
+	}
+
+	reg.Version = CurrentVersion
 	data, err := json.MarshalIndent(reg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal: %w", err)
 	}
 
-	if err := os.WriteFile(p, data, 0644); err != nil {
+	backup := ""
+	if existing != nil && !bytes.Equal(existing, data) {
+		if backup, err = writeBackup(p, existing); err != nil {
+			return err
+		}
+	}
+
+	if err := atomicfile.WriteFile(p, data, 0644); err != nil {
+		if backup != "" {
+			os.Remove(filepath.Join(BackupDir(p), backup))
+		}
 		return err
 	}
 
+	// Only journal a change once it has been made.
+	if backup != "" {
+		rotateBackups(p, backup, existing, reg)
+	}
+
 	slog.Info("Saved locations registry", "path", p, "count", len(reg.Locations))
 	return nil
 }
@@ -2,6 +2,7 @@ package locations
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -183,3 +184,119 @@ func TestRegistryJSONRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func labeledRegistry() *Registry {
+	return &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "1725 slough avenue", Label: "office"},
+			{ID: "loc-2", CanonicalAddress: "123 kellum court"},
+			{ID: "loc-3", CanonicalAddress: "5561 moseley road"},
+		},
+		NextID: 4,
+	}
+}
+
+func TestRegistryFind(t *testing.T) {
+	registry := labeledRegistry()
+
+	t.Run("find by ID", func(t *testing.T) {
+		loc, err := registry.Find("loc-2")
+		if err != nil {
+			t.Fatalf("Find() failed: %v", err)
+		}
+		if loc.ID != "loc-2" {
+			t.Errorf("expected loc-2, got %s", loc.ID)
+		}
+	})
+
+	t.Run("find by label ignoring case", func(t *testing.T) {
+		loc, err := registry.Find("Office")
+		if err != nil {
+			t.Fatalf("Find() failed: %v", err)
+		}
+		if loc.ID != "loc-1" {
+			t.Errorf("expected loc-1, got %s", loc.ID)
+		}
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		_, err := registry.Find("airport")
+		if !errors.Is(err, ErrLocationNotFound) {
+			t.Errorf("expected ErrLocationNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRegistrySetLabel(t *testing.T) {
+	t.Run("set and clear label", func(t *testing.T) {
+		registry := labeledRegistry()
+
+		if err := registry.SetLabel("loc-2", " home "); err != nil {
+			t.Fatalf("SetLabel() failed: %v", err)
+		}
+		if registry.Label("loc-2") != "home" {
+			t.Errorf("expected label home, got %q", registry.Label("loc-2"))
+		}
+
+		if err := registry.SetLabel("home", ""); err != nil {
+			t.Fatalf("SetLabel() failed: %v", err)
+		}
+		if registry.Label("loc-2") != "" {
+			t.Errorf("expected label to be cleared, got %q", registry.Label("loc-2"))
+		}
+	})
+
+	t.Run("relabel same location", func(t *testing.T) {
+		registry := labeledRegistry()
+
+		if err := registry.SetLabel("office", "OFFICE"); err != nil {
+			t.Fatalf("SetLabel() failed: %v", err)
+		}
+		if registry.Label("loc-1") != "OFFICE" {
+			t.Errorf("expected label OFFICE, got %q", registry.Label("loc-1"))
+		}
+	})
+
+	t.Run("duplicate label", func(t *testing.T) {
+		registry := labeledRegistry()
+
+		err := registry.SetLabel("loc-2", "office")
+		if !errors.Is(err, ErrDuplicateLabel) {
+			t.Errorf("expected ErrDuplicateLabel, got %v", err)
+		}
+	})
+
+	t.Run("label that looks like an ID", func(t *testing.T) {
+		registry := labeledRegistry()
+
+		err := registry.SetLabel("loc-2", "loc-9")
+		if !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("expected ErrInvalidLabel, got %v", err)
+		}
+	})
+}
+
+func TestRegistryDelete(t *testing.T) {
+	registry := labeledRegistry()
+
+	deleted, err := registry.Delete("office")
+	if err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+
+	if deleted.ID != "loc-1" {
+		t.Errorf("expected loc-1 to be deleted, got %s", deleted.ID)
+	}
+
+	if len(registry.Locations) != 2 {
+		t.Errorf("expected 2 locations, got %d", len(registry.Locations))
+	}
+
+	if _, err := registry.Find("loc-1"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("expected deleted location to be gone, got %v", err)
+	}
+
+	if _, err := registry.Delete("loc-1"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("expected ErrLocationNotFound, got %v", err)
+	}
+}
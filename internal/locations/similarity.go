@@ -0,0 +1,246 @@
+package locations
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	DefaultMinConfidence = 0.8
+
+	// fuzzyRadiusFactor is how many location radii away a fuzzy address
+	// match is still considered. Confidence falls linearly to zero there.
+	fuzzyRadiusFactor = 8.0
+
+	minPrefixLength   = 3
+	minTypoLength     = 4
+	minTypoSimilarity = 0.8
+)
+
+// AddressSimilarity compares two normalized addresses token by token and
+// returns a score between 0 and 1. Tokens match when equal, when one is an
+// abbreviation-like prefix of the other, or when they differ by a small typo.
+// Addresses with house numbers that have none in common score 0.
+func AddressSimilarity(a, b string) float64 {
+	score, _, _ := addressSimilarity(a, b)
+	return score
+}
+
+func addressSimilarity(a, b string) (float64, []string, bool) {
+	ta, tb := addressTokens(a), addressTokens(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0, nil, false
+	}
+
+	if numbersConflict(ta, tb) {
+		return 0, nil, true
+	}
+
+	if len(ta) > len(tb) {
+		ta, tb = tb, ta
+	}
+
+	used := make([]bool, len(tb))
+	var common []string
+	for _, t := range ta {
+		for j, u := range tb {
+			if !used[j] && tokensMatch(t, u) {
+				used[j] = true
+				common = append(common, t)
+				break
+			}
+		}
+	}
+
+	matched := float64(len(common))
+	containment := matched / float64(len(ta))
+	dice := 2 * matched / float64(len(ta)+len(tb))
+	return (containment + dice) / 2, common, false
+}
+
+func addressTokens(address string) []string {
+	var tokens []string
+	for _, t := range strings.Fields(address) {
+		if strings.Trim(t, "-/.#") != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+func numbersConflict(a, b []string) bool {
+	na := slices.DeleteFunc(slices.Clone(a), func(t string) bool { return !hasDigit(t) })
+	nb := slices.DeleteFunc(slices.Clone(b), func(t string) bool { return !hasDigit(t) })
+	if len(na) == 0 || len(nb) == 0 {
+		return false
+	}
+	for _, n := range na {
+		if slices.Contains(nb, n) {
+			return false
+		}
+	}
+	return true
+}
+
+func tokensMatch(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if hasDigit(a) || hasDigit(b) {
+		return false
+	}
+	short, long := a, b
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	if len(short) >= minPrefixLength && strings.HasPrefix(long, short) {
+		return true
+	}
+	if len(short) < minTypoLength {
+		return false
+	}
+	similarity := 1 - float64(levenshtein(a, b))/float64(max(len([]rune(a)), len([]rune(b))))
+	return similarity >= minTypoSimilarity
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// MatchExplanation records how a new address and position compare to a
+// location and whether the processor would merge them.
+type MatchExplanation struct {
+	Address         string
+	LocationAddress string
+	Distance        float64
+	Radius          float64
+	Similarity      float64
+	CommonTokens    []string
+	NumbersConflict bool
+	Confidence      float64
+	MinConfidence   float64
+	Match           bool
+	Reason          string
+}
+
+// Explain reports whether a visit at address and (lat, lon) would be merged
+// into loc, comparing against the location's canonical address and every
+// variant and keeping the best one.
+func (p *Processor) Explain(address string, lat, lon float64, loc Location) MatchExplanation {
+	return p.explain(p.normalizer.Normalize(address), lat, lon, loc)
+}
+
+func (p *Processor) explain(address string, lat, lon float64, loc Location) MatchExplanation {
+	e := MatchExplanation{
+		Address:         address,
+		LocationAddress: loc.CanonicalAddress,
+		Distance:        HaversineDistance(lat, lon, loc.AvgLat, loc.AvgLon),
+		Radius:          p.radius(loc),
+		MinConfidence:   p.config.MinConfidence,
+	}
+
+	candidates := slices.Concat([]string{loc.CanonicalAddress}, loc.AddressVariants)
+	exact := address != "" && slices.Contains(candidates, address)
+
+	e.Similarity = -1
+	for _, candidate := range candidates {
+		if exact && candidate != address {
+			continue
+		}
+		score, common, conflict := addressSimilarity(address, candidate)
+		if score > e.Similarity {
+			e.LocationAddress = candidate
+			e.Similarity = score
+			e.CommonTokens = common
+			e.NumbersConflict = conflict
+		}
+	}
+
+	e.Confidence = e.Similarity * distanceFactor(e.Distance, e.Radius)
+
+	switch {
+	case exact:
+		e.Match = true
+		e.Reason = "same normalized address"
+	case e.Distance <= e.Radius:
+		e.Match = true
+		e.Reason = fmt.Sprintf("within the %.0f m radius", e.Radius)
+	case e.NumbersConflict:
+		e.Reason = "house numbers differ"
+	case e.Confidence >= e.MinConfidence:
+		e.Match = true
+		e.Reason = fmt.Sprintf("confidence %.2f is at least %.2f", e.Confidence, e.MinConfidence)
+	case e.Distance > e.Radius*fuzzyRadiusFactor:
+		e.Reason = fmt.Sprintf("more than %.0f m away", e.Radius*fuzzyRadiusFactor)
+	default:
+		e.Reason = fmt.Sprintf("confidence %.2f is below %.2f", e.Confidence, e.MinConfidence)
+	}
+
+	return e
+}
+
+// distanceFactor is 1 within the radius and falls linearly to 0 at
+// fuzzyRadiusFactor times the radius.
+func distanceFactor(distance, radius float64) float64 {
+	if distance <= radius {
+		return 1
+	}
+	limit := radius * fuzzyRadiusFactor
+	if distance >= limit {
+		return 0
+	}
+	return 1 - (distance-radius)/(limit-radius)
+}
+
+// fuzzyReach is the distance from a location with the given radius beyond
+// which distanceFactor, and so the confidence of any address match, falls
+// below minConfidence.
+func fuzzyReach(radius, minConfidence float64) float64 {
+	return radius * (1 + (fuzzyRadiusFactor-1)*(1-min(minConfidence, 1)))
+}
+
+// findLocationFuzzy returns the nearby location whose addresses best match
+// address with at least the configured confidence, or -1.
+func (p *Processor) findLocationFuzzy(address string, lat, lon float64) int {
+	if address == "" || p.config.MinConfidence > 1 {
+		return -1
+	}
+
+	match := -1
+	best := 0.0
+	reach := func(radius float64) float64 { return fuzzyReach(radius, p.config.MinConfidence) }
+	for _, i := range p.nearby(lat, lon, reach) {
+		loc := p.registry.Locations[i]
+		if HaversineDistance(lat, lon, loc.AvgLat, loc.AvgLon) > reach(p.radius(loc)) {
+			continue
+		}
+
+		e := p.explain(address, lat, lon, loc)
+		if e.NumbersConflict || e.Confidence < p.config.MinConfidence {
+			continue
+		}
+		if e.Confidence > best || (e.Confidence == best && i < match) {
+			match = i
+			best = e.Confidence
+		}
+	}
+	return match
+}
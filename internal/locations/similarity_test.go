@@ -0,0 +1,135 @@
+package locations
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAddressSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want float64
+	}{
+		{"identical", "1725 slough avenue", "1725 slough avenue", 1},
+		{"abbreviated and extra suffix", "1725 slough ave", "1725 slough avenue - scranton", (1 + 6.0/7) / 2},
+		{"typo", "1725 slogh avenue", "1725 slough avenue", 1},
+		{"different house number", "1726 slough avenue", "1725 slough avenue", 0},
+		{"unrelated", "rua augusta", "avenida paulista", 0},
+		{"empty", "", "rua augusta", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AddressSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("AddressSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func fuzzyRegistry() *Registry {
+	return &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "1725 slough avenue - scranton", AddressVariants: []string{}, AvgLat: 41.4089, AvgLon: -75.6624, VisitCount: 3},
+		},
+		NextID: 2,
+	}
+}
+
+func TestFindOrCreateLocationFuzzy(t *testing.T) {
+	// About 42 m away, outside the 25 m threshold.
+	const lat, lon = 41.4092, -75.6621
+
+	t.Run("similar address merges", func(t *testing.T) {
+		processor := NewProcessor(fuzzyRegistry())
+		if locID := processor.FindOrCreateLocation("1725 Slough Ave", lat, lon, Visit{}); locID != "loc-1" {
+			t.Errorf("expected fuzzy match to loc-1, got %s", locID)
+		}
+	})
+
+	t.Run("different house number does not merge", func(t *testing.T) {
+		processor := NewProcessor(fuzzyRegistry())
+		if locID := processor.FindOrCreateLocation("1727 Slough Ave", lat, lon, Visit{}); locID != "loc-2" {
+			t.Errorf("expected a new location, got %s", locID)
+		}
+	})
+
+	t.Run("too far away does not merge", func(t *testing.T) {
+		processor := NewProcessor(fuzzyRegistry())
+		if locID := processor.FindOrCreateLocation("1725 Slough Ave", 41.4110, -75.6624, Visit{}); locID != "loc-2" {
+			t.Errorf("expected a new location, got %s", locID)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		processor := NewProcessorWithConfig(fuzzyRegistry(), Config{MinConfidence: 1.1})
+		if locID := processor.FindOrCreateLocation("1725 Slough Ave", lat, lon, Visit{}); locID != "loc-2" {
+			t.Errorf("expected fuzzy matching to be disabled, got %s", locID)
+		}
+	})
+}
+
+func TestFuzzyReach(t *testing.T) {
+	for _, radius := range []float64{25, 300, 2000} {
+		for _, confidence := range []float64{0.5, 0.8, 1} {
+			reach := fuzzyReach(radius, confidence)
+			if got := distanceFactor(reach, radius); math.Abs(got-confidence) > 1e-9 {
+				t.Errorf("radius %v, confidence %v: distance factor at reach %v is %v", radius, confidence, reach, got)
+			}
+			if reach > radius*fuzzyRadiusFactor {
+				t.Errorf("radius %v, confidence %v: reach %v beyond the fuzzy limit", radius, confidence, reach)
+			}
+		}
+	}
+}
+
+func TestFindOrCreateLocationFuzzyWideRadius(t *testing.T) {
+	registry := &Registry{
+		Locations: []Location{
+			{ID: "loc-1", CanonicalAddress: "aeroporto internacional guarulhos", AddressVariants: []string{}, AvgLat: -23.4356, AvgLon: -46.4731, VisitCount: 1, Radius: 1000},
+		},
+		NextID: 2,
+	}
+	processor := NewProcessorWithConfig(registry, Config{MinConfidence: 0.8})
+
+	// 1.3 km away is outside the radius but within the 2.4 km fuzzy reach.
+	lat := -23.4356 - 1300/metersPerDegree
+	if locID := processor.Locate("aeroporto internacional guarulho", lat, -46.4731, Visit{}); locID != "loc-1" {
+		t.Errorf("expected fuzzy match within the reach of the wide radius, got %q", locID)
+	}
+	if locID := processor.Locate("aeroporto internacional guarulho", -23.4356-3000/metersPerDegree, -46.4731, Visit{}); locID != "" {
+		t.Errorf("expected no match beyond the fuzzy reach, got %q", locID)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	registry := fuzzyRegistry()
+	processor := NewProcessor(registry)
+	loc := registry.Locations[0]
+
+	tests := []struct {
+		name      string
+		address   string
+		lat, lon  float64
+		wantMatch bool
+		reason    string
+	}{
+		{"same address", "1725 Slough Avenue - Scranton", 41.4200, -75.6624, true, "same normalized address"},
+		{"within radius", "Dunder Mifflin", 41.4090, -75.6624, true, "within the 25 m radius"},
+		{"fuzzy", "1725 Slough Ave", 41.4092, -75.6621, true, "confidence 0.84 is at least 0.80"},
+		{"house numbers", "1727 Slough Ave", 41.4092, -75.6621, false, "house numbers differ"},
+		{"far", "1725 Slough Ave", 41.4200, -75.6624, false, "more than 200 m away"},
+		{"low confidence", "Scranton Business Park", 41.4092, -75.6621, false, "confidence 0.28 is below 0.80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := processor.Explain(tt.address, tt.lat, tt.lon, loc)
+			if e.Match != tt.wantMatch || e.Reason != tt.reason {
+				t.Errorf("Explain() = %v (%s), want %v (%s)", e.Match, e.Reason, tt.wantMatch, tt.reason)
+			}
+		})
+	}
+}
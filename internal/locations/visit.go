@@ -0,0 +1,54 @@
+package locations
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type VisitRole string
+
+const (
+	VisitPickup  VisitRole = "pickup"
+	VisitDropoff VisitRole = "dropoff"
+)
+
+// ParseVisitRole parses "pickup" or "dropoff". An empty string or "any"
+// returns the empty role, meaning either end of a trip.
+func ParseVisitRole(s string) (VisitRole, error) {
+	switch r := VisitRole(strings.ToLower(strings.TrimSpace(s))); r {
+	case "", "any":
+		return "", nil
+	case VisitPickup, VisitDropoff:
+		return r, nil
+	default:
+		return "", fmt.Errorf("unsupported trip end: %s (expected pickup, dropoff or any)", s)
+	}
+}
+
+type Visit struct {
+	TripUUID string    `json:"tripUUID,omitempty"`
+	Role     VisitRole `json:"role,omitempty"`
+	Time     time.Time `json:"time"`
+	Lat      float64   `json:"lat"`
+	Lon      float64   `json:"lon"`
+}
+
+type visitKey struct {
+	tripUUID string
+	role     VisitRole
+}
+
+func (v Visit) key() (visitKey, bool) {
+	if v.TripUUID == "" {
+		return visitKey{}, false
+	}
+	return visitKey{tripUUID: v.TripUUID, role: v.Role}, true
+}
+
+func sortVisits(visits []Visit) {
+	slices.SortStableFunc(visits, func(a, b Visit) int {
+		return a.Time.Compare(b.Time)
+	})
+}
@@ -91,6 +91,38 @@ func Fare(s string) float64 {
 	return val
 }
 
+var currencySymbols = map[string]string{
+	"R$":  "BRL",
+	"$":   "USD",
+	"US$": "USD",
+	"€":   "EUR",
+	"£":   "GBP",
+	"MX$": "MXN",
+	"CA$": "CAD",
+}
+
+func Currency(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+
+	if matches := currencyRegex.FindStringSubmatch(s); len(matches) >= 2 {
+		return s[:3]
+	}
+
+	symbol := strings.TrimRightFunc(s, func(r rune) bool {
+		return (r >= '0' && r <= '9') || r == '.' || r == ','
+	})
+	if symbol == s {
+		symbol = strings.TrimLeftFunc(s, func(r rune) bool {
+			return (r >= '0' && r <= '9') || r == '.' || r == ','
+		})
+	}
+
+	return currencySymbols[strings.TrimSpace(symbol)]
+}
+
 func Rating(s string) int {
 	if s == "" {
 		return 0
@@ -137,6 +137,53 @@ func TestFare(t *testing.T) {
 	}
 }
 
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Brazilian Real format",
+			input: "R$10.84",
+			want:  "BRL",
+		},
+		{
+			name:  "ISO code format",
+			input: "USD$25.50",
+			want:  "USD",
+		},
+		{
+			name:  "dollar sign",
+			input: "$12.00",
+			want:  "USD",
+		},
+		{
+			name:  "trailing euro sign",
+			input: "12.00 €",
+			want:  "EUR",
+		},
+		{
+			name:  "simple number",
+			input: "15.99",
+			want:  "",
+		},
+		{
+			name:  "empty string",
+			input: "",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Currency(tt.input); got != tt.want {
+				t.Errorf("Currency(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRating(t *testing.T) {
 	tests := []struct {
 		name  string
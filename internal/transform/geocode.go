@@ -0,0 +1,42 @@
+package transform
+
+import (
+	"uber-extractor/internal/geocode"
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+// GeocodeTrips sets the neighbourhood, city and country of both ends of every
+// trip from the gazetteer.
+func GeocodeTrips(tripList []trips.Trip, g *geocode.Gazetteer) {
+	for i := range tripList {
+		trip := &tripList[i]
+
+		pickup := g.Reverse(trip.PickupLat, trip.PickupLon)
+		trip.PickupNeighbourhood = pickup.Neighbourhood
+		trip.PickupCity = pickup.City
+		trip.PickupCountry = pickup.Country
+
+		dropoff := g.Reverse(trip.DropoffLat, trip.DropoffLon)
+		trip.DropoffNeighbourhood = dropoff.Neighbourhood
+		trip.DropoffCity = dropoff.City
+		trip.DropoffCountry = dropoff.Country
+	}
+}
+
+// GeocodeLocations sets the neighbourhood, city and country of every location
+// from its average coordinates and returns how many locations changed.
+func GeocodeLocations(registry *locations.Registry, g *geocode.Gazetteer) int {
+	changed := 0
+	for i := range registry.Locations {
+		loc := &registry.Locations[i]
+		place := g.Reverse(loc.AvgLat, loc.AvgLon)
+		if loc.Neighbourhood != place.Neighbourhood || loc.City != place.City || loc.Country != place.Country {
+			loc.Neighbourhood = place.Neighbourhood
+			loc.City = place.City
+			loc.Country = place.Country
+			changed++
+		}
+	}
+	return changed
+}
@@ -0,0 +1,51 @@
+package transform
+
+import (
+	"testing"
+
+	"uber-extractor/internal/geocode"
+	"uber-extractor/internal/locations"
+	"uber-extractor/internal/trips"
+)
+
+func testGazetteer() *geocode.Gazetteer {
+	return geocode.NewGazetteer([]geocode.Entry{
+		{Name: "Scranton", Kind: geocode.KindCity, Country: "US", Lat: 41.40916, Lon: -75.6649},
+		{Name: "Downtown", Kind: geocode.KindNeighbourhood, City: "Scranton", Country: "US", Lat: 41.4089, Lon: -75.6624},
+	})
+}
+
+func TestGeocodeTrips(t *testing.T) {
+	tripList := []trips.Trip{
+		{UUID: "trip-001", PickupLat: 41.4089, PickupLon: -75.6624, DropoffLat: 41.4500, DropoffLon: -75.6200},
+	}
+
+	GeocodeTrips(tripList, testGazetteer())
+
+	trip := tripList[0]
+	if trip.PickupNeighbourhood != "Downtown" || trip.PickupCity != "Scranton" || trip.PickupCountry != "US" {
+		t.Errorf("unexpected pickup place: %q, %q, %q", trip.PickupNeighbourhood, trip.PickupCity, trip.PickupCountry)
+	}
+	if trip.DropoffNeighbourhood != "" || trip.DropoffCity != "Scranton" {
+		t.Errorf("unexpected dropoff place: %q, %q", trip.DropoffNeighbourhood, trip.DropoffCity)
+	}
+}
+
+func TestGeocodeLocations(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{
+			{ID: "loc-1", AvgLat: 41.4089, AvgLon: -75.6624},
+			{ID: "loc-2", AvgLat: -23.5614, AvgLon: -46.6559},
+		},
+	}
+
+	if changed := GeocodeLocations(registry, testGazetteer()); changed != 1 {
+		t.Errorf("expected 1 location to change, got %d", changed)
+	}
+	if loc := registry.Locations[0]; loc.DisplayAddress() != "Downtown, Scranton, US" {
+		t.Errorf("expected place as display address, got %q", loc.DisplayAddress())
+	}
+	if changed := GeocodeLocations(registry, testGazetteer()); changed != 0 {
+		t.Errorf("expected no changes on a second run, got %d", changed)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"uber-extractor/internal/uberapi"
 )
 
-func ProcessTrip(resp *uberapi.GetTripResponse, lp *locations.Processor) (trips.Trip, error) {
+// ProcessTrip converts a trip details response into a trip. Locations are
+// assigned separately, with AssignLocations or LocateTrips.
+func ProcessTrip(resp *uberapi.GetTripResponse) (trips.Trip, error) {
 	tripData := resp.Data.GetTrip.Trip
 
 	trip := trips.Trip{
@@ -31,6 +33,7 @@ func ProcessTrip(resp *uberapi.GetTripResponse, lp *locations.Processor) (trips.
 	}
 
 	trip.Fare = parser.Fare(tripData.Fare)
+	trip.Currency = parser.Currency(tripData.Fare)
 
 	duration, err := parser.Duration(resp.Data.GetTrip.Receipt.Duration)
 	if err == nil {
@@ -52,10 +55,60 @@ func ProcessTrip(resp *uberapi.GetTripResponse, lp *locations.Processor) (trips.
 		trip.DropoffAddress = tripData.Waypoints[len(tripData.Waypoints)-1]
 	}
 
-	if lp != nil && trips.ParseTripStatus(tripData.Status) == trips.StatusCompleted {
-		trip.PickupLocationID = lp.FindOrCreateLocation(trip.PickupAddress, trip.PickupLat, trip.PickupLon)
-		trip.DropoffLocationID = lp.FindOrCreateLocation(trip.DropoffAddress, trip.DropoffLat, trip.DropoffLon)
+	return trip, nil
+}
+
+func AssignLocations(tripList []trips.Trip, lp *locations.Processor) {
+	var points []locations.Point
+	var completed []int
+	for i, trip := range tripList {
+		if trip.Status != trips.StatusCompleted {
+			continue
+		}
+		completed = append(completed, i)
+		points = append(points,
+			locations.Point{Address: trip.PickupAddress, Lat: trip.PickupLat, Lon: trip.PickupLon, Visit: pickupVisit(trip)},
+			locations.Point{Address: trip.DropoffAddress, Lat: trip.DropoffLat, Lon: trip.DropoffLon, Visit: dropoffVisit(trip)},
+		)
 	}
 
-	return trip, nil
+	ids := lp.AssignBatch(points)
+
+	registry := lp.Registry()
+	for n, i := range completed {
+		trip := &tripList[i]
+		trip.PickupLocationID = ids[2*n]
+		trip.DropoffLocationID = ids[2*n+1]
+		trip.PickupLabel = registry.Label(trip.PickupLocationID)
+		trip.DropoffLabel = registry.Label(trip.DropoffLocationID)
+	}
+}
+
+// LocateTrips points completed trips at the existing locations they started
+// and ended at, without recording visits or creating locations; ends that
+// match no location are left unassigned.
+func LocateTrips(tripList []trips.Trip, lp *locations.Processor) {
+	registry := lp.Registry()
+	for i := range tripList {
+		trip := &tripList[i]
+		if trip.Status != trips.StatusCompleted {
+			continue
+		}
+		trip.PickupLocationID = lp.Locate(trip.PickupAddress, trip.PickupLat, trip.PickupLon, pickupVisit(*trip))
+		trip.DropoffLocationID = lp.Locate(trip.DropoffAddress, trip.DropoffLat, trip.DropoffLon, dropoffVisit(*trip))
+		trip.PickupLabel = registry.Label(trip.PickupLocationID)
+		trip.DropoffLabel = registry.Label(trip.DropoffLocationID)
+	}
+}
+
+func pickupVisit(trip trips.Trip) locations.Visit {
+	return locations.Visit{TripUUID: trip.UUID, Role: locations.VisitPickup, Time: trip.BeginTime}
+}
+
+func dropoffVisit(trip trips.Trip) locations.Visit {
+	t := trip.EndTime
+	if t.IsZero() {
+		t = trip.BeginTime
+	}
+	return locations.Visit{TripUUID: trip.UUID, Role: locations.VisitDropoff, Time: t}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	"uber-extractor/internal/locations"
 	"uber-extractor/internal/trips"
@@ -22,7 +23,7 @@ func TestProcessTrip(t *testing.T) {
 			t.Fatalf("failed to unmarshal trip details: %v", err)
 		}
 
-		trip, err := ProcessTrip(&response, nil)
+		trip, err := ProcessTrip(&response)
 		if err != nil {
 			t.Fatalf("ProcessTrip() failed: %v", err)
 		}
@@ -47,6 +48,10 @@ func TestProcessTrip(t *testing.T) {
 			t.Errorf("expected fare %v, got %v", 17.65, trip.Fare)
 		}
 
+		if trip.Currency != "BRL" {
+			t.Errorf("expected currency %s, got %s", "BRL", trip.Currency)
+		}
+
 		if trip.Distance != 8.63 {
 			t.Errorf("expected distance %v, got %v", 8.63, trip.Distance)
 		}
@@ -96,7 +101,7 @@ func TestProcessTrip(t *testing.T) {
 		}
 	})
 
-	t.Run("process trip with location processor", func(t *testing.T) {
+	t.Run("process trip and assign locations", func(t *testing.T) {
 		data, err := os.ReadFile("testdata/trip_details_obfuscated.json")
 		if err != nil {
 			t.Fatalf("failed to read test data: %v", err)
@@ -114,11 +119,15 @@ func TestProcessTrip(t *testing.T) {
 
 		lp := locations.NewProcessor(registry)
 
-		trip, err := ProcessTrip(&response, lp)
+		trip, err := ProcessTrip(&response)
 		if err != nil {
 			t.Fatalf("ProcessTrip() failed: %v", err)
 		}
 
+		tripList := []trips.Trip{trip}
+		AssignLocations(tripList, lp)
+		trip = tripList[0]
+
 		if trip.PickupLocationID == "" {
 			t.Error("expected pickup location ID to be set")
 		}
@@ -130,6 +139,27 @@ func TestProcessTrip(t *testing.T) {
 		if len(registry.Locations) != 2 {
 			t.Errorf("expected 2 locations to be created, got %d", len(registry.Locations))
 		}
+
+		if err := registry.SetLabel(trip.PickupLocationID, "office"); err != nil {
+			t.Fatalf("SetLabel() failed: %v", err)
+		}
+
+		trip, err = ProcessTrip(&response)
+		if err != nil {
+			t.Fatalf("ProcessTrip() failed: %v", err)
+		}
+
+		tripList = []trips.Trip{trip}
+		AssignLocations(tripList, lp)
+		trip = tripList[0]
+
+		if trip.PickupLabel != "office" {
+			t.Errorf("expected pickup label office, got %q", trip.PickupLabel)
+		}
+
+		if trip.DropoffLabel != "" {
+			t.Errorf("expected empty dropoff label, got %q", trip.DropoffLabel)
+		}
 	})
 }
 
@@ -173,11 +203,15 @@ func TestProcessCanceledTrip(t *testing.T) {
 
 	lp := locations.NewProcessor(registry)
 
-	trip, err := ProcessTrip(&response, lp)
+	trip, err := ProcessTrip(&response)
 	if err != nil {
 		t.Fatalf("ProcessTrip() failed: %v", err)
 	}
 
+	tripList := []trips.Trip{trip}
+	AssignLocations(tripList, lp)
+	trip = tripList[0]
+
 	if trip.Status != trips.StatusCanceled {
 		t.Errorf("expected status %v, got %v", trips.StatusCanceled, trip.Status)
 	}
@@ -198,3 +232,104 @@ func TestProcessCanceledTrip(t *testing.T) {
 		t.Error("expected no location ID for canceled trip")
 	}
 }
+
+func TestAssignLocations(t *testing.T) {
+	tripList := []trips.Trip{
+		{
+			UUID:           "trip-001",
+			Status:         trips.StatusCompleted,
+			BeginTime:      time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+			EndTime:        time.Date(2024, 1, 15, 8, 20, 0, 0, time.UTC),
+			PickupAddress:  "1725 Slough Avenue",
+			PickupLat:      41.4089,
+			PickupLon:      -75.6624,
+			DropoffAddress: "123 Kellum Court",
+			DropoffLat:     41.4120,
+			DropoffLon:     -75.6580,
+		},
+		{
+			UUID:          "trip-002",
+			Status:        trips.StatusCanceled,
+			PickupAddress: "1725 Slough Avenue",
+			PickupLat:     41.4089,
+			PickupLon:     -75.6624,
+		},
+		{
+			UUID:           "trip-003",
+			Status:         trips.StatusCompleted,
+			PickupAddress:  "123 Kellum Court",
+			PickupLat:      41.4120,
+			PickupLon:      -75.6580,
+			DropoffAddress: "1725 Slough Avenue",
+			DropoffLat:     41.4089,
+			DropoffLon:     -75.6624,
+		},
+	}
+
+	registry := &locations.Registry{Locations: []locations.Location{}, NextID: 1}
+	lp := locations.NewProcessorWithConfig(registry, locations.Config{Algorithm: locations.AlgorithmDBSCAN})
+
+	AssignLocations(tripList, lp)
+
+	if tripList[0].PickupLocationID != "loc-1" || tripList[0].DropoffLocationID != "loc-2" {
+		t.Errorf("unexpected location IDs for first trip: %s, %s", tripList[0].PickupLocationID, tripList[0].DropoffLocationID)
+	}
+
+	if tripList[1].PickupLocationID != "" {
+		t.Error("expected no location ID for canceled trip")
+	}
+
+	if tripList[2].PickupLocationID != "loc-2" || tripList[2].DropoffLocationID != "loc-1" {
+		t.Errorf("unexpected location IDs for third trip: %s, %s", tripList[2].PickupLocationID, tripList[2].DropoffLocationID)
+	}
+
+	if len(registry.Locations) != 2 {
+		t.Errorf("expected 2 locations, got %d", len(registry.Locations))
+	}
+
+	kellum, _ := registry.Find("loc-2")
+	if want := tripList[0].EndTime; !kellum.FirstSeen.Equal(want) {
+		t.Errorf("expected first seen at dropoff time %v, got %v", want, kellum.FirstSeen)
+	}
+
+	AssignLocations(tripList, locations.NewProcessorWithConfig(registry, locations.Config{Algorithm: locations.AlgorithmDBSCAN}))
+
+	if kellum, _ := registry.Find("loc-2"); kellum.VisitCount != 2 {
+		t.Errorf("expected re-ingested trips not to be counted again, got %d visits", kellum.VisitCount)
+	}
+}
+
+func TestLocateTrips(t *testing.T) {
+	registry := &locations.Registry{
+		Locations: []locations.Location{
+			{ID: "loc-1", Label: "office", CanonicalAddress: "1725 slough avenue", AvgLat: 41.4089, AvgLon: -75.6624, VisitCount: 1},
+		},
+		NextID: 2,
+	}
+
+	tripList := []trips.Trip{
+		{
+			UUID:           "trip-001",
+			Status:         trips.StatusCompleted,
+			BeginTime:      time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+			PickupAddress:  "1725 Slough Ave",
+			PickupLat:      41.4090,
+			PickupLon:      -75.6623,
+			DropoffAddress: "123 Kellum Court",
+			DropoffLat:     41.4120,
+			DropoffLon:     -75.6580,
+		},
+	}
+
+	LocateTrips(tripList, locations.NewProcessor(registry))
+
+	if tripList[0].PickupLocationID != "loc-1" || tripList[0].PickupLabel != "office" {
+		t.Errorf("expected pickup at the office, got %q (%q)", tripList[0].PickupLocationID, tripList[0].PickupLabel)
+	}
+	if tripList[0].DropoffLocationID != "" {
+		t.Errorf("expected unknown dropoff to stay unassigned, got %q", tripList[0].DropoffLocationID)
+	}
+	if len(registry.Locations) != 1 || registry.Locations[0].VisitCount != 1 || registry.NextID != 2 {
+		t.Errorf("expected registry to be left untouched, got %+v", registry)
+	}
+}
@@ -0,0 +1,34 @@
+package trips
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func LoadFile(path string) ([]Trip, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trips file: %w", err)
+	}
+
+	var tripList []Trip
+	if err := json.Unmarshal(data, &tripList); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trips: %w", err)
+	}
+
+	return tripList, nil
+}
+
+func SaveFile(path string, tripList []Trip) error {
+	data, err := json.MarshalIndent(tripList, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trips: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write trips file: %w", err)
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ type Trip struct {
 	EndTime           time.Time  `json:"endTime"`
 	Status            TripStatus `json:"status"`
 	Fare              float64    `json:"fare"`
+	Currency          string     `json:"currency"`
 	Driver            string     `json:"driver"`
 	VehicleType       string     `json:"vehicleType"`
 	Distance          float64    `json:"distance"`
@@ -26,6 +27,17 @@ type Trip struct {
 	MapURL            string     `json:"mapUrl"`
 	PickupLocationID  string     `json:"pickupLocationID"`
 	DropoffLocationID string     `json:"dropoffLocationID"`
+	PickupLabel       string     `json:"pickupLabel,omitempty"`
+	DropoffLabel      string     `json:"dropoffLabel,omitempty"`
+
+	PickupNeighbourhood  string `json:"pickupNeighbourhood,omitempty"`
+	PickupCity           string `json:"pickupCity,omitempty"`
+	PickupCountry        string `json:"pickupCountry,omitempty"`
+	DropoffNeighbourhood string `json:"dropoffNeighbourhood,omitempty"`
+	DropoffCity          string `json:"dropoffCity,omitempty"`
+	DropoffCountry       string `json:"dropoffCountry,omitempty"`
+
+	CommuteType string `json:"commuteType,omitempty"`
 }
 
 type TripSummary struct {